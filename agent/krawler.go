@@ -2,14 +2,14 @@ package main
 
 import (
 	"bytes"
-	"crypto/x509"
 	"encoding/json"
-	"encoding/pem"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -22,25 +22,148 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 )
 
+const textfileName = "krawler.prom"
+
 func main() {
 	var (
-		result string
-		err    error
+		textfileDir string
+		interval    time.Duration
+		at          string
+		in          time.Duration
+		read        string
 	)
 
-	hostName := os.Getenv("NODENAME")
-	if hostName == "" {
-		ReadorDie("/etc/hostname")
+	flag.StringVar(&textfileDir, "textfile-dir", "", "if set, write entries as prometheus metrics into this node_exporter textfile collector directory instead of json to stdout")
+	flag.DurationVar(&interval, "interval", 0, "with --textfile-dir, rewrite the metrics in this interval. 0 means only once")
+	flag.StringVar(&read, "read", "", "print the file at this path on the host instead of entries, for images without cat")
+	flag.StringVar(&at, "at", "", "evaluate days at this RFC3339 time instead of now")
+	flag.DurationVar(&in, "in", 0, "evaluate days at this duration from now, e.g. 720h")
+	flag.Parse()
+
+	if read != "" {
+		dirs := []string{defaultReadDir}
+		if env := os.Getenv(readDirsEnv); env != "" {
+			dirs = strings.Split(env, ":")
+		}
+		body, err := ReadHostFile(hostRootPath, dirs, read)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(body)
+		return
 	}
 
-	result, err = Execute([]string{"sh", "-c", "grep -rw kubelet /tmp/proc/*/comm | cut -d'/' -f4"})
+	evaluationTime, err := EvaluationTime(at, in)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	if at != "" || in != 0 {
+		now = func() time.Time { return evaluationTime }
+	}
+
+	hostName := os.Getenv("NODENAME")
+	if hostName == "" {
+		hostName, err = ReadFile("/etc/hostname")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if textfileDir == "" {
+		entries, err := Collect(hostName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		// the clock of the node, not the evaluation time
+		nodeTime := time.Now()
+		o := Output{
+			Entries: entries,
+			Now:     &nodeTime,
+		}
+
+		buffer := &bytes.Buffer{}
+		if err := json.NewEncoder(buffer).Encode(o); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		// print to stdout
+		fmt.Print(buffer.String())
+		return
+	}
 
-	commands := GetCommandsFromCmdline(
-		ReadorDie(fmt.Sprintf("/tmp/proc/%s/cmdline", result)))
+	// a failed collection is recorded in the metrics and tried again in the next interval
+	for {
+		entries, err := Collect(hostName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			entries = []Entry{NewErrorEntry(hostName, "Error", "", err)}
+		}
+		if err := WriteTextfileAtomic(path.Join(textfileDir, textfileName), entries); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if interval <= 0 {
+			return
+		}
+		time.Sleep(interval)
+	}
+}
+
+// ReadHostFile reads the file at path of the host which is mounted at root.
+// Files out of dirs of the host are refused.
+func ReadHostFile(root string, dirs []string, path string) (string, error) {
+	path = filepath.Clean("/" + path)
+	allowed := false
+	for _, d := range dirs {
+		d = filepath.Clean("/" + d)
+		if d != "/" && strings.HasPrefix(path, d+"/") {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", fmt.Errorf("%s is not in the directories mounted from the host: %s", path, strings.Join(dirs, ", "))
+	}
+	body, err := ioutil.ReadFile(filepath.Join(root, path))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// EvaluationTime returns the time given by at in RFC3339 or the duration in from now
+func EvaluationTime(at string, in time.Duration) (time.Time, error) {
+	if at != "" && in != 0 {
+		return time.Time{}, fmt.Errorf("only one of --at and --in can be given")
+	}
+	if at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("--at must be RFC3339, e.g. 2020-01-02T15:04:05Z: %s", err)
+		}
+		return t, nil
+	}
+	return time.Now().Add(in), nil
+}
+
+// Collect gathers kubelet certification entries of this node.
+// A certification which can not be read is an entry with the error as its warning,
+// and an error is returned only when the kubelet or its configuration can not be found.
+func Collect(hostName string) ([]Entry, error) {
+	result, err := Execute([]string{"sh", "-c", "grep -rw kubelet /tmp/proc/*/comm | cut -d'/' -f4"})
+	if err != nil {
+		return nil, err
+	}
+
+	cmdline, err := ReadFile(fmt.Sprintf("/tmp/proc/%s/cmdline", result))
+	if err != nil {
+		return nil, err
+	}
+	commands := GetCommandsFromCmdline(cmdline)
 
 	var (
 		tempCertPath = ""
@@ -52,16 +175,17 @@ func main() {
 		isClientRotateCert    = false
 		isServerRotateCert    = false
 		kubeletServerCertPath = path.Join(defaultKubeletServerCertPath, "kubelet.crt")
+		kubeletServerKeyPath  = path.Join(defaultKubeletServerCertPath, "kubelet.key")
 	)
 
 	configData := map[string]interface{}{}
 	if configPath, ok := commands[configFlag]; ok {
-		err = yaml.Unmarshal(
-			[]byte(ReadorDie(configPath)), &configData)
-
+		config, err := ReadFile(configPath)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			return nil, err
+		}
+		if err := yaml.Unmarshal([]byte(config), &configData); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", configPath, err)
 		}
 	}
 
@@ -85,7 +209,7 @@ func main() {
 
 	for _, v := range strings.Split(commands[featureGatesFlag], ",") {
 		value := strings.Split(v, "=")
-		if value[0] == rotateKubeletServerCertFeature {
+		if value[0] == rotateKubeletServerCertFeature && len(value) > 1 {
 			isServerRotateCertB = cast.ToBool(value[1])
 		}
 	}
@@ -99,17 +223,27 @@ func main() {
 
 	if tempCertPath != "" && tempKeyPath != "" {
 		kubeletServerCertPath = tempCertPath
+		kubeletServerKeyPath = tempKeyPath
 	} else if isServerRotateCert {
+		// the rotated certification has its key in the same file
 		kubeletServerCertPath = path.Join(defaultKubeletServerCertPath, "kubelet-server-current.pem")
+		kubeletServerKeyPath = kubeletServerCertPath
 	} else if data, ok := commands[certDirFlag]; ok {
 		kubeletServerCertPath = path.Join(data, "kubelet.crt")
+		kubeletServerKeyPath = path.Join(data, "kubelet.key")
 	}
 
-	date, days := GetDateAndDaysFromCert(
-		ReadorDie(kubeletServerCertPath))
-
 	e := []Entry{}
-	e = append(e, *NewEntry(hostName, "server-cert", days, date, kubeletServerCertPath))
+	// e.g. the certification is being rotated, it is read again by the next collection
+	if serverCert, err := ReadFile(kubeletServerCertPath); err != nil {
+		e = append(e, NewErrorEntry(hostName, "server-cert", kubeletServerCertPath, err))
+	} else {
+		serverEntries := NewEntries(hostName, "server-cert", serverCert, kubeletServerCertPath)
+		if !serverEntries[0].Due.IsZero() {
+			serverEntries[0].Warning = CheckKeyPairFile(serverCert, kubeletServerKeyPath)
+		}
+		e = append(e, serverEntries...)
+	}
 
 	if data, ok := commands[rotateCertFlag]; ok {
 		isClientRotateCert = cast.ToBool(data)
@@ -120,59 +254,60 @@ func main() {
 	// TODO: also return this value
 	_ = isClientRotateCert
 
-	if kubeConfigPath, ok := commands[kubeConfigFlag]; ok {
-		cfg, err := clientcmd.NewClientConfigFromBytes(
-			[]byte(ReadorDie(kubeConfigPath)))
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		rawConfig, err := cfg.RawConfig()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-
-		var (
-			kubeletClientCertFile string
-			kubeletClientCertPath string
-		)
-		currentContext := rawConfig.CurrentContext
-		user := rawConfig.Contexts[currentContext].AuthInfo
-		u := rawConfig.AuthInfos[user]
-
-		if string(u.ClientCertificateData) != "" {
-			kubeletClientCertFile = string(u.ClientCertificateData)
-			kubeletClientCertPath = kubeConfigPath
-		} else if string(u.ClientCertificate) != "" {
-			kubeletClientCertFile = ReadorDie(strings.Trim(string(u.ClientCertificate), "\n"))
-			kubeletClientCertPath = string(u.ClientCertificate)
-		} else {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-
-		date, days := GetDateAndDaysFromCert(kubeletClientCertFile)
-
-		e = append(e, *NewEntry(hostName, "client-cert", days, date, kubeletClientCertPath))
+	kubeConfigPath, ok := commands[kubeConfigFlag]
+	if !ok {
+		return nil, fmt.Errorf("kubelet has no --%s", kubeConfigFlag)
+	}
+	kubeConfig, err := ReadFile(kubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := clientcmd.NewClientConfigFromBytes([]byte(kubeConfig))
+	if err != nil {
+		return nil, err
+	}
+	rawConfig, err := cfg.RawConfig()
+	if err != nil {
+		return nil, err
+	}
 
-	} else {
-		fmt.Println(err)
-		os.Exit(1)
+	var (
+		kubeletClientCertFile string
+		kubeletClientCertPath string
+	)
+	currentContext, ok := rawConfig.Contexts[rawConfig.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("current context %q is not in %s", rawConfig.CurrentContext, kubeConfigPath)
+	}
+	u, ok := rawConfig.AuthInfos[currentContext.AuthInfo]
+	if !ok {
+		return nil, fmt.Errorf("user %q is not in %s", currentContext.AuthInfo, kubeConfigPath)
 	}
 
-	o := Output{
-		Entries: e,
+	if string(u.ClientCertificateData) != "" {
+		kubeletClientCertFile = string(u.ClientCertificateData)
+		kubeletClientCertPath = kubeConfigPath
+	} else if string(u.ClientCertificate) != "" {
+		kubeletClientCertPath = strings.Trim(string(u.ClientCertificate), "\n")
+		kubeletClientCertFile, err = ReadFile(kubeletClientCertPath)
+		if err != nil {
+			return append(e, NewErrorEntry(hostName, "client-cert", kubeletClientCertPath, err)), nil
+		}
+	} else {
+		return nil, fmt.Errorf("user %q has no client certificate in %s", currentContext.AuthInfo, kubeConfigPath)
 	}
 
-	buffer := &bytes.Buffer{}
-	if err := json.NewEncoder(buffer).Encode(o); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	clientEntries := NewEntries(hostName, "client-cert", kubeletClientCertFile, kubeletClientCertPath)
+	if !clientEntries[0].Due.IsZero() {
+		if string(u.ClientKeyData) != "" {
+			clientEntries[0].Warning = CheckKeyPair(kubeletClientCertFile, string(u.ClientKeyData), kubeConfigPath)
+		} else if u.ClientKey != "" {
+			clientEntries[0].Warning = CheckKeyPairFile(kubeletClientCertFile, strings.Trim(u.ClientKey, "\n"))
+		}
 	}
+	e = append(e, clientEntries...)
 
-	// print to stdout
-	fmt.Print(buffer.String())
+	return e, nil
 }
 
 // Execute run command and return it's output
@@ -187,31 +322,12 @@ func Execute(cmd []string) (string, error) {
 		out, err = exec.Command(cmd[0]).Output()
 	}
 	if err != nil {
-		fmt.Printf("Failed to execute command: %s\n", strings.Join(cmd, " "))
-		return "", err
+		return "", fmt.Errorf("failed to execute command: %s: %s", strings.Join(cmd, " "), err)
 	}
 
 	return strings.Trim(string(out), "\n"), nil
 }
 
-// GetDateAndDaysFromCert takes a cert and extract Date and Days
-func GetDateAndDaysFromCert(cert string) (time.Time, int) {
-	timeNow := time.Now()
-	block, _ := pem.Decode([]byte(cert))
-	if block == nil {
-		fmt.Println("failed to parse certificate PEM")
-		os.Exit(1)
-	}
-	c, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		fmt.Println("failed to parse certificate: " + err.Error())
-		os.Exit(1)
-	}
-	expiresIn := int(c.NotAfter.Sub(timeNow).Hours() / 24)
-
-	return c.NotAfter, expiresIn
-}
-
 // GetCommandsFromCmdline takes a cmdline and parse it to several commands
 func GetCommandsFromCmdline(cmdline string) map[string]string {
 	var (
@@ -235,13 +351,12 @@ func GetCommandsFromCmdline(cmdline string) map[string]string {
 	return cmds
 }
 
-// ReadorDie read fileName file and return it's content
-func ReadorDie(fileName string) string {
+// ReadFile read fileName file and return it's content
+func ReadFile(fileName string) (string, error) {
 	body, err := ioutil.ReadFile(fileName)
 	if err != nil {
-		fmt.Println("Read file fail: " + fileName + err.Error())
-		os.Exit(1)
+		return "", fmt.Errorf("read file fail: %s: %s", fileName, err.Error())
 	}
 
-	return strings.Trim(string(body), "\n")
+	return strings.Trim(string(body), "\n"), nil
 }
@@ -2,9 +2,18 @@ package main
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io/ioutil"
+	"math/big"
 	"os"
+	"path"
 	"strings"
 	"testing"
 	"time"
@@ -136,3 +145,177 @@ func TestJsonEncode(t *testing.T) {
 	t.Log(buffer.String())
 	assert.Equal(t, "{\"entry\":[{\"type\":\"kubelet\",\"node\":\"node\",\"name\":\"name\",\"days\":1,\"due\":\"2019-01-01T00:00:00Z\",\"path\":\"path\"}]}\n", buffer.String())
 }
+
+func TestWriteTextfile(t *testing.T) {
+	e := []Entry{
+		*NewEntry("node", "server-cert", 1, time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC), "/var/lib/kubelet/pki/kubelet.crt"),
+		*NewEntry("node", "client-cert", 1, time.Date(2019, time.January, 2, 0, 0, 0, 0, time.UTC), "C:\\\"kubelet\".conf"),
+	}
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, WriteTextfile(buffer, e))
+
+	assert.Equal(t, "# HELP kube_cert_expiry_timestamp_seconds Expiration of the certification in seconds since epoch.\n"+
+		"# TYPE kube_cert_expiry_timestamp_seconds gauge\n"+
+		"kube_cert_expiry_timestamp_seconds{type=\"kubelet\",node=\"node\",name=\"server-cert\",path=\"/var/lib/kubelet/pki/kubelet.crt\"} 1546300800\n"+
+		"kube_cert_expiry_timestamp_seconds{type=\"kubelet\",node=\"node\",name=\"client-cert\",path=\"C:\\\\\\\"kubelet\\\".conf\"} 1546387200\n"+
+		"# HELP kube_cert_collection_errors Number of certifications which could not be read in the last collection.\n"+
+		"# TYPE kube_cert_collection_errors gauge\n"+
+		"kube_cert_collection_errors{node=\"node\"} 0\n", buffer.String())
+}
+
+func TestWriteTextfileWithErrors(t *testing.T) {
+	e := []Entry{
+		*NewEntry("node", "server-cert", 1, time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC), "/var/lib/kubelet/pki/kubelet.crt"),
+		NewErrorEntry("node", "client-cert", "/var/lib/kubelet/pki/kubelet-client-current.pem", fmt.Errorf("failed to parse certificate PEM")),
+	}
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, WriteTextfile(buffer, e))
+
+	assert.NotContains(t, buffer.String(), "name=\"client-cert\"")
+	assert.Contains(t, buffer.String(), "kube_cert_collection_errors{node=\"node\"} 1\n")
+}
+
+func TestWriteTextfileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "krawler")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fileName := path.Join(dir, textfileName)
+	e := []Entry{*NewEntry("node", "server-cert", 1, time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC), "path")}
+	assert.NoError(t, WriteTextfileAtomic(fileName, e))
+	assert.NoError(t, WriteTextfileAtomic(fileName, e))
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, textfileName, files[0].Name())
+}
+
+func newSelfSignedCert(t *testing.T, notAfter time.Time) string {
+	cert, _ := newSelfSignedKeyPair(t, notAfter)
+	return cert
+}
+
+// newSelfSignedKeyPair returns PEM of a self signed certification and its private key
+func newSelfSignedKeyPair(t *testing.T, notAfter time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kubelet"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+func TestNewEntriesOfBundle(t *testing.T) {
+	first := time.Now().AddDate(1, 0, 0).UTC().Truncate(time.Second)
+	second := time.Now().AddDate(0, 0, 10).UTC().Truncate(time.Second)
+	bundle := newSelfSignedCert(t, first) + "\n" + newSelfSignedCert(t, second)
+
+	e := NewEntries("node", "server-cert", bundle, "path")
+
+	assert.Equal(t, 3, len(e))
+	assert.Equal(t, second, e[0].Due)
+	assert.Equal(t, 0, e[0].Position)
+	assert.Equal(t, first, e[1].Due)
+	assert.Equal(t, 1, e[1].Position)
+	assert.Equal(t, rootRole, e[1].Role)
+	assert.Equal(t, second, e[2].Due)
+	assert.Equal(t, 2, e[2].Position)
+
+	e = NewEntries("node", "server-cert", newSelfSignedCert(t, first), "path")
+	assert.Equal(t, 1, len(e))
+}
+
+func TestNewEntriesOfBrokenPEM(t *testing.T) {
+	// e.g. a certification read while it is rotated
+	cert := newSelfSignedCert(t, time.Now().AddDate(1, 0, 0))
+	e := NewEntries("node", "server-cert", cert[:len(cert)/2], "path")
+
+	assert.Equal(t, 1, len(e))
+	assert.True(t, e[0].Due.IsZero())
+	assert.Equal(t, "server-cert", e[0].Name)
+	assert.Equal(t, "failed to parse certificate PEM", e[0].Warning)
+}
+
+func TestCheckKeyPair(t *testing.T) {
+	cert, key := newSelfSignedKeyPair(t, time.Now().AddDate(1, 0, 0))
+	_, otherKey := newSelfSignedKeyPair(t, time.Now().AddDate(1, 0, 0))
+
+	assert.Equal(t, "", CheckKeyPair(cert, key, "kubelet.key"))
+	// the rotated certification has the key in the same file
+	assert.Equal(t, "", CheckKeyPair(cert+key, cert+key, "kubelet-server-current.pem"))
+
+	warning := CheckKeyPair(cert, otherKey, "kubelet.key")
+	assert.Contains(t, warning, "private key kubelet.key does not match")
+	assert.NotContains(t, warning, strings.TrimSpace(otherKey))
+
+	assert.Contains(t, CheckKeyPairFile(cert, "/not/exists/kubelet.key"), "failed to read private key /not/exists/kubelet.key")
+}
+
+func TestNewEntriesShipOnlyCertificates(t *testing.T) {
+	cert, key := newSelfSignedKeyPair(t, time.Now().AddDate(1, 0, 0))
+
+	e := NewEntries("node", "server-cert", cert+key, "kubelet-server-current.pem")
+
+	assert.Equal(t, cert, e[0].Certificate)
+	assert.NotContains(t, e[0].Certificate, "PRIVATE KEY")
+}
+
+func TestEvaluationTime(t *testing.T) {
+	at, err := EvaluationTime("2020-06-01T00:00:00Z", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC), at)
+
+	at, err = EvaluationTime("", time.Hour)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), at, time.Minute)
+
+	_, err = EvaluationTime("2020-06-01T00:00:00Z", time.Hour)
+	assert.Error(t, err)
+	_, err = EvaluationTime("tomorrow", 0)
+	assert.Error(t, err)
+}
+
+func TestReadHostFile(t *testing.T) {
+	root, err := ioutil.TempDir("", "host")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	assert.NoError(t, os.MkdirAll(path.Join(root, "etc/kubernetes/pki"), 0755))
+	assert.NoError(t, ioutil.WriteFile(path.Join(root, "etc/kubernetes/pki/apiserver.crt"), []byte("cert\n"), 0644))
+
+	dirs := []string{"/etc/kubernetes"}
+	body, err := ReadHostFile(root, dirs, "/etc/kubernetes/pki/apiserver.crt")
+	assert.NoError(t, err)
+	assert.Equal(t, "cert\n", body)
+
+	// paths never go out of the root
+	_, err = ReadHostFile(root, dirs, "../../etc/kubernetes/pki/apiserver.crt")
+	assert.NoError(t, err)
+
+	_, err = ReadHostFile(root, dirs, "/etc/kubernetes/not-exists")
+	assert.Error(t, err)
+
+	// files out of the mounted directories are refused even if they exist under the root
+	assert.NoError(t, os.MkdirAll(path.Join(root, "etc/shadow-dir"), 0755))
+	assert.NoError(t, ioutil.WriteFile(path.Join(root, "etc/shadow-dir/secret"), []byte("secret\n"), 0644))
+	for _, p := range []string{"/etc/shadow-dir/secret", "/etc/kubernetes/../shadow-dir/secret", "/etc/kubernetes", "/etc/kubernetes-other/ca.crt"} {
+		_, err = ReadHostFile(root, dirs, p)
+		if assert.Error(t, err, p) {
+			assert.Contains(t, err.Error(), "is not in the directories mounted from the host")
+		}
+	}
+	_, err = ReadHostFile(root, []string{"/"}, "/etc/shadow-dir/secret")
+	assert.Error(t, err)
+}
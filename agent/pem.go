@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+)
+
+// now is the time entries are evaluated at, which can be moved by --at or --in
+var now = time.Now
+
+// roles of a certificate in a PEM bundle
+const (
+	leafRole         = "leaf"
+	intermediateRole = "intermediate"
+	rootRole         = "root"
+)
+
+// GetCertificatesFromPEM takes PEM data and parses every certificate in it
+func GetCertificatesFromPEM(cert string) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	rest := []byte(cert)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		// e.g. kubelet-server-current.pem has the private key too
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %s", err.Error())
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("failed to parse certificate PEM")
+	}
+
+	return certs, nil
+}
+
+func encodeCertificates(certs []*x509.Certificate) string {
+	buffer := &bytes.Buffer{}
+	for _, c := range certs {
+		pem.Encode(buffer, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+	}
+	return buffer.String()
+}
+
+// NewErrorEntry makes an entry of a certification which could not be read
+func NewErrorEntry(node string, name string, path string, err error) Entry {
+	return Entry{
+		Type:    entryType,
+		Node:    node,
+		Name:    name,
+		Path:    path,
+		Warning: err.Error(),
+	}
+}
+
+// CheckKeyPair returns a warning when key is not the private key of the certification.
+// The key itself is never a part of the warning.
+func CheckKeyPair(cert string, key string, keyPath string) string {
+	if _, err := tls.X509KeyPair([]byte(cert), []byte(key)); err != nil {
+		return fmt.Sprintf("private key %s does not match the certification: %s", keyPath, err.Error())
+	}
+	return ""
+}
+
+// CheckKeyPairFile reads the key at keyPath and checks it against the certification
+func CheckKeyPairFile(cert string, keyPath string) string {
+	key, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Sprintf("failed to read private key %s: %s", keyPath, err.Error())
+	}
+	return CheckKeyPair(cert, string(key), keyPath)
+}
+
+// GetDateAndDaysFromCert takes a cert and extract Date and Days of the earliest expiring certificate in it
+func GetDateAndDaysFromCert(cert string) (time.Time, int, error) {
+	certs, err := GetCertificatesFromPEM(cert)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	earliest := earliestCertificate(certs)
+
+	return earliest.NotAfter, daysUntil(earliest.NotAfter), nil
+}
+
+// NewEntries makes an entry of the earliest expiry in cert,
+// followed by every certificate with its position if cert is a bundle.
+// If cert can not be parsed, the entry has no due but the error as its warning.
+func NewEntries(node string, name string, cert string, path string) []Entry {
+	certs, err := GetCertificatesFromPEM(cert)
+	if err != nil {
+		return []Entry{NewErrorEntry(node, name, path, err)}
+	}
+	earliest := earliestCertificate(certs)
+	fe := NewEntry(node, name, daysUntil(earliest.NotAfter), earliest.NotAfter, path)
+	fe.Details = NewDetails(earliest)
+	fe.Certificate = encodeCertificates(certs)
+	e := []Entry{*fe}
+
+	if len(certs) == 1 {
+		return e
+	}
+	for i, c := range certs {
+		ce := NewEntry(node, name, daysUntil(c.NotAfter), c.NotAfter, path)
+		ce.Position = i + 1
+		ce.Role = chainRole(c)
+		ce.Details = NewDetails(c)
+		e = append(e, *ce)
+	}
+	return e
+}
+
+func earliestCertificate(certs []*x509.Certificate) *x509.Certificate {
+	earliest := certs[0]
+	for _, c := range certs[1:] {
+		if c.NotAfter.Before(earliest.NotAfter) {
+			earliest = c
+		}
+	}
+	return earliest
+}
+
+// chainRole tells whether a certificate is a self signed root, an intermediate CA or a leaf
+func chainRole(c *x509.Certificate) string {
+	if bytes.Equal(c.RawSubject, c.RawIssuer) && c.CheckSignatureFrom(c) == nil {
+		return rootRole
+	}
+	if c.IsCA {
+		return intermediateRole
+	}
+	return leafRole
+}
+
+// NewDetails describes a certificate
+func NewDetails(c *x509.Certificate) *Details {
+	ips := []string{}
+	for _, ip := range c.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	fingerprint := sha256.Sum256(c.Raw)
+
+	return &Details{
+		Subject:            c.Subject.String(),
+		Issuer:             c.Issuer.String(),
+		DNSNames:           c.DNSNames,
+		IPAddresses:        ips,
+		SerialNumber:       colonHex(c.SerialNumber.Bytes()),
+		KeyAlgorithm:       c.PublicKeyAlgorithm.String(),
+		KeySize:            keySize(c.PublicKey),
+		SignatureAlgorithm: c.SignatureAlgorithm.String(),
+		NotBefore:          c.NotBefore,
+		Fingerprint:        colonHex(fingerprint[:]),
+	}
+}
+
+func keySize(publicKey interface{}) int {
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	}
+	return 0
+}
+
+// colonHex formats bytes like openssl, e.g. 0A:1B
+func colonHex(b []byte) string {
+	s := make([]string, len(b))
+	for i := range b {
+		s[i] = fmt.Sprintf("%02X", b[i])
+	}
+	return strings.Join(s, ":")
+}
+
+func daysUntil(t time.Time) int {
+	return daysBetween(now(), t)
+}
+
+// daysBetween floors the days so that a certification expired hours ago is -1 day, not 0
+func daysBetween(from time.Time, t time.Time) int {
+	return int(math.Floor(t.Sub(from).Hours() / 24))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	expiryMetric = "kube_cert_expiry_timestamp_seconds"
+	expiryHelp   = "Expiration of the certification in seconds since epoch."
+	errorsMetric = "kube_cert_collection_errors"
+	errorsHelp   = "Number of certifications which could not be read in the last collection."
+)
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// WriteTextfile writes entries in the prometheus text exposition format.
+// Entries which could not be read are counted by the errors metric instead of their expiry.
+func WriteTextfile(w io.Writer, entries []Entry) error {
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", expiryMetric, expiryHelp, expiryMetric); err != nil {
+		return err
+	}
+	errors := map[string]int{}
+	nodes := []string{}
+	for _, e := range entries {
+		if _, ok := errors[e.Node]; !ok {
+			nodes = append(nodes, e.Node)
+			errors[e.Node] = 0
+		}
+		if e.Due.IsZero() {
+			errors[e.Node]++
+			continue
+		}
+		// a bundle is exported once by its earliest expiry to keep series unique
+		if e.Position > 0 {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "%s{type=\"%s\",node=\"%s\",name=\"%s\",path=\"%s\"} %d\n",
+			expiryMetric,
+			labelValueEscaper.Replace(e.Type),
+			labelValueEscaper.Replace(e.Node),
+			labelValueEscaper.Replace(e.Name),
+			labelValueEscaper.Replace(e.Path),
+			e.Due.Unix())
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", errorsMetric, errorsHelp, errorsMetric); err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if _, err := fmt.Fprintf(w, "%s{node=\"%s\"} %d\n", errorsMetric, labelValueEscaper.Replace(node), errors[node]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTextfileAtomic replaces fileName with entries so node_exporter never reads a partial file
+func WriteTextfileAtomic(fileName string, entries []Entry) error {
+	buffer := &bytes.Buffer{}
+	if err := WriteTextfile(buffer, entries); err != nil {
+		return err
+	}
+
+	// the temporary file has to be in the same directory to be renamed atomically,
+	// node_exporter only reads files ending with .prom
+	tmp, err := ioutil.TempFile(filepath.Dir(fileName), filepath.Base(fileName)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buffer.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fileName)
+}
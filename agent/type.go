@@ -27,11 +27,19 @@ const (
 	defaultKubeletServerCertPath = "/var/lib/kubelet/pki/"
 
 	entryType = "kubelet"
+
+	// directories of the host with files of control plane are mounted under here by check-cert
+	hostRootPath = "/host"
+	// readDirsEnv has the mounted directories separated by colons, only files in them are read
+	readDirsEnv    = "KRAWLER_READ_DIRS"
+	defaultReadDir = "/etc/kubernetes"
 )
 
 // Output is for json stdout
 type Output struct {
 	Entries []Entry `json:"entry"`
+	// Now is the time of the node when the entries are collected to find skew of its clock
+	Now *time.Time `json:"now,omitempty"`
 }
 
 // Entry is for cert entries
@@ -42,6 +50,30 @@ type Entry struct {
 	Days int       `json:"days"`
 	Due  time.Time `json:"due"`
 	Path string    `json:"path"`
+	// Position and Role are only set for each certificate of a bundle, starting from 1
+	Position int    `json:"position,omitempty"`
+	Role     string `json:"role,omitempty"`
+
+	Details *Details `json:"details,omitempty"`
+
+	// public PEM of the certificates, shipped by the agent to verify the chain
+	Certificate string `json:"certificate,omitempty"`
+	// Warning is a finding of the agent about the certification, e.g. a mismatched private key
+	Warning string `json:"warning,omitempty"`
+}
+
+// Details describes the certificate of an entry
+type Details struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	DNSNames           []string  `json:"dnsNames,omitempty"`
+	IPAddresses        []string  `json:"ipAddresses,omitempty"`
+	SerialNumber       string    `json:"serialNumber"`
+	KeyAlgorithm       string    `json:"keyAlgorithm"`
+	KeySize            int       `json:"keySize"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	NotBefore          time.Time `json:"notBefore"`
+	Fingerprint        string    `json:"sha256Fingerprint"`
 }
 
 // NewEntry make entry
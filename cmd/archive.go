@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	archiveExample = `
+	# view expiration of every certification and kubeconfig in a backup of /etc/kubernetes taken by kubeadm
+	%[1]s check-cert archive etc-kubernetes.tar.gz
+
+	# view them in support bundles of several nodes
+	%[1]s check-cert archive node1.tar.gz node2.tar.gz --summary
+`
+
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// ArchiveOptions provides information to scan certifications in tar archives without apiserver
+type ArchiveOptions struct {
+	*ExpirationOptions
+
+	nodeName string
+}
+
+// NewCmdArchive provides a cobra command scanning certifications in tar archives
+func NewCmdArchive(o *ExpirationOptions) *cobra.Command {
+	a := &ArchiveOptions{
+		ExpirationOptions: o,
+	}
+
+	cmd := &cobra.Command{
+		Use:          "archive FILE... [flags]",
+		Short:        "View expiration days of certifications and kubeconfigs in tar or tar.gz archives like backups and support bundles",
+		Example:      fmt.Sprintf(archiveExample, "kubectl"),
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			o.setOutputFlagSpecified(c)
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return a.Run(args)
+		},
+	}
+
+	cmd.Flags().StringVar(&a.nodeName, "node-name", a.nodeName, "node name of the certifications, the name of the archive if it is empty")
+	o.addReportFlags(cmd)
+
+	return cmd
+}
+
+// Run scans certifications in every archive and reports them
+func (a *ArchiveOptions) Run(archives []string) error {
+	certs := []serverCertification{}
+	for _, archive := range archives {
+		nodeName := a.nodeName
+		if nodeName == "" {
+			nodeName = filepath.Base(archive)
+		}
+		certs = append(certs, scanArchive(archive, nodeName)...)
+	}
+	a.audit(certs, nil)
+	sortCertifications(certs, defaultSort)
+	return a.report(certs)
+}
+
+// scanArchive reads every PEM certification and kubeconfig in a tar archive which may be gzipped
+func scanArchive(archive string, nodeName string) []serverCertification {
+	files, err := readArchive(archive)
+	if err != nil {
+		return []serverCertification{{
+			Entry: Entry{
+				Type: fileEntryType,
+				Node: nodeName,
+				Name: "Error",
+				Path: archive,
+			},
+			Warning: err.Error(),
+		}}
+	}
+
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	certs := []serverCertification{}
+	for _, name := range names {
+		dir := path.Dir(name)
+		certs = append(certs, scanFile(archive+":"+name, path.Base(name), files[name], nodeName, func(p string) (string, string, error) {
+			found, body, err := files.readFile(dir, p)
+			if err != nil {
+				return p, "", err
+			}
+			return archive + ":" + found, body, nil
+		})...)
+	}
+	return certs
+}
+
+// archiveFiles are contents of regular files in an archive by their cleaned names
+type archiveFiles map[string][]byte
+
+// readArchive reads regular files of a tar archive which may be gzipped, large files are skipped
+func readArchive(archive string) (archiveFiles, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	files := archiveFiles{}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", archive, err)
+		}
+		if !h.FileInfo().Mode().IsRegular() || h.Size > maxScanFileSize {
+			continue
+		}
+		body, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s in %s: %s", h.Name, archive, err)
+		}
+		files[path.Clean(strings.TrimPrefix(h.Name, "/"))] = body
+	}
+	return files, nil
+}
+
+// readFile reads a file a kubeconfig in dir of the archive refers to and returns its name in the archive with its content.
+// Archives have files under prefixes like `etc/kubernetes` or `sosreport-node1/etc/kubernetes`,
+// so an absolute path is matched with the longest suffix of it which is the end of a file name, e.g.
+// `/etc/kubernetes/pki/ca.crt` with `backup/kubernetes/pki/ca.crt`.
+// Files under the same prefix as dir are preferred for archives of several nodes.
+func (files archiveFiles) readFile(dir string, p string) (string, string, error) {
+	if !path.IsAbs(p) {
+		name := path.Join(dir, p)
+		if body, ok := files[name]; ok {
+			return name, string(body), nil
+		}
+		return "", "", fmt.Errorf("%s is not in the archive", p)
+	}
+
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	components := strings.Split(strings.TrimPrefix(path.Clean(p), "/"), "/")
+	// the file name alone is too ambiguous, e.g. ca.crt
+	for i := 0; i < len(components)-1; i++ {
+		suffix := strings.Join(components[i:], "/")
+		found := ""
+		for _, name := range names {
+			if name != suffix && !strings.HasSuffix(name, "/"+suffix) {
+				continue
+			}
+			if strings.HasPrefix(dir+"/", strings.TrimSuffix(name, suffix)) {
+				return name, string(files[name]), nil
+			}
+			if found == "" {
+				found = name
+			}
+		}
+		if found != "" {
+			return found, string(files[found]), nil
+		}
+	}
+	return "", "", fmt.Errorf("%s is not in the archive", p)
+}
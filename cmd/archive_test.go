@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestArchive writes files to a tar archive at path, gzipped if compress is true
+func writeTestArchive(t *testing.T, path string, files map[string]string, compress bool) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	if compress {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+	for name, body := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const testKubeletConf = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority: /etc/kubernetes/pki/ca.crt
+    server: https://10.0.0.1:6443
+  name: kubernetes
+contexts:
+- context:
+    cluster: kubernetes
+    user: default-auth
+  name: default
+current-context: default
+users:
+- name: default-auth
+  user:
+    client-certificate: /var/lib/kubelet/pki/kubelet-client-current.pem
+    client-key: /var/lib/kubelet/pki/kubelet-client-current.pem
+`
+
+func TestScanArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	due := time.Now().Add(100 * 24 * time.Hour).UTC().Truncate(time.Second)
+	ca := newTestCA(t, "kubernetes", time.Now().Add(3650*24*time.Hour), nil)
+	apiserver := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "kube-apiserver"}, NotBefore: time.Now(), NotAfter: due}, ca)
+	node1 := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "system:node:node1"}, NotBefore: time.Now(), NotAfter: due}, ca)
+	node2 := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "system:node:node2"}, NotBefore: time.Now(), NotAfter: due.Add(24 * time.Hour)}, ca)
+
+	archive := filepath.Join(dir, "bundle.tar.gz")
+	writeTestArchive(t, archive, map[string]string{
+		"node1/etc/kubernetes/pki/ca.crt":                      ca.pem,
+		"node1/etc/kubernetes/pki/apiserver.crt":               apiserver.pem,
+		"node1/etc/kubernetes/pki/apiserver.key":               apiserver.keyPem,
+		"node1/etc/kubernetes/kubelet.conf":                    testKubeletConf,
+		"node1/var/lib/kubelet/pki/kubelet-client-current.pem": node1.pem + node1.keyPem,
+		"node2/etc/kubernetes/kubelet.conf":                    testKubeletConf,
+		"node2/var/lib/kubelet/pki/kubelet-client-current.pem": node2.pem + node2.keyPem,
+		"node2/var/log/kubelet.log":                            "no certification",
+	}, true)
+
+	certs := scanArchive(archive, "bundle.tar.gz")
+
+	assert.Equal(t, 8, len(certs))
+	byPath := map[string]serverCertification{}
+	for _, c := range certs {
+		assert.Equal(t, "bundle.tar.gz", c.Entry.Node)
+		assert.Equal(t, "", c.Warning, c.Entry.Path)
+		byPath[c.Entry.Name+" "+c.Entry.Path] = c
+	}
+
+	assert.Equal(t, due, byPath["apiserver.crt "+archive+":node1/etc/kubernetes/pki/apiserver.crt"].Entry.Due)
+	// node2 has no CA, it is found under node1
+	assert.Equal(t, 2, len(filterByName(certs, "cluster/kubernetes", archive+":node1/etc/kubernetes/pki/ca.crt")))
+	// the kubelet certification of each node is found under its own prefix
+	assert.Equal(t, due, byPath["user/default-auth "+archive+":node1/var/lib/kubelet/pki/kubelet-client-current.pem"].Entry.Due)
+	assert.Equal(t, due.Add(24*time.Hour), byPath["user/default-auth "+archive+":node2/var/lib/kubelet/pki/kubelet-client-current.pem"].Entry.Due)
+}
+
+func filterByName(certs []serverCertification, name string, path string) []serverCertification {
+	result := []serverCertification{}
+	for _, c := range certs {
+		if c.Entry.Name == name && c.Entry.Path == path {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func TestScanArchiveWithoutGzip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	due := time.Now().Add(100 * 24 * time.Hour).UTC().Truncate(time.Second)
+	archive := filepath.Join(dir, "pki.tar")
+	writeTestArchive(t, archive, map[string]string{"ca.crt": newTestCert(t, "kubernetes", time.Now(), due)}, false)
+
+	certs := scanArchive(archive, "node1")
+
+	assert.Equal(t, 1, len(certs))
+	assert.Equal(t, "ca.crt", certs[0].Entry.Name)
+	assert.Equal(t, archive+":ca.crt", certs[0].Entry.Path)
+	assert.Equal(t, due, certs[0].Entry.Due)
+
+	// files at the top of the archive are named by themselves and find their private keys
+	ca := newTestCA(t, "kubernetes", due, nil)
+	other := newTestCA(t, "other", due, nil)
+	archive = filepath.Join(dir, "top.tar")
+	writeTestArchive(t, archive, map[string]string{"ca.crt": ca.pem, "ca.key": other.keyPem}, false)
+
+	certs = scanArchive(archive, "node1")
+	assert.Equal(t, 1, len(certs))
+	assert.Equal(t, "ca.crt", certs[0].Entry.Name)
+	assert.Contains(t, certs[0].Warning, "private key "+archive+":ca.key does not match the certification")
+
+	broken := filepath.Join(dir, "broken.tar.gz")
+	if err := ioutil.WriteFile(broken, []byte{0x1f, 0x8b, 0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	certs = scanArchive(broken, "node1")
+	assert.Equal(t, 1, len(certs))
+	assert.Equal(t, "Error", certs[0].Entry.Name)
+}
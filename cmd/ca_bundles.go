@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const caBundleName = "caBundle"
+
+// caBundleSource describes where caBundles are in a kind of objects
+type caBundleSource struct {
+	entryType string
+	// served versions of the resource, preferred first
+	resources []schema.GroupVersionResource
+	// bundles returns base64 encoded caBundles of an object by their path
+	bundles func(obj *unstructured.Unstructured) map[string]string
+}
+
+var caBundleSources = []caBundleSource{
+	{
+		entryType: "mutatingwebhook",
+		resources: []schema.GroupVersionResource{
+			{Group: "admissionregistration.k8s.io", Version: "v1", Resource: "mutatingwebhookconfigurations"},
+			{Group: "admissionregistration.k8s.io", Version: "v1beta1", Resource: "mutatingwebhookconfigurations"},
+		},
+		bundles: webhookCABundles,
+	},
+	{
+		entryType: "validatingwebhook",
+		resources: []schema.GroupVersionResource{
+			{Group: "admissionregistration.k8s.io", Version: "v1", Resource: "validatingwebhookconfigurations"},
+			{Group: "admissionregistration.k8s.io", Version: "v1beta1", Resource: "validatingwebhookconfigurations"},
+		},
+		bundles: webhookCABundles,
+	},
+	{
+		entryType: "apiservice",
+		resources: []schema.GroupVersionResource{
+			{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"},
+			{Group: "apiregistration.k8s.io", Version: "v1beta1", Resource: "apiservices"},
+		},
+		bundles: fieldCABundle("spec", "caBundle"),
+	},
+	{
+		entryType: "crd",
+		resources: []schema.GroupVersionResource{
+			{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"},
+			{Group: "apiextensions.k8s.io", Version: "v1beta1", Resource: "customresourcedefinitions"},
+		},
+		bundles: func(obj *unstructured.Unstructured) map[string]string {
+			// conversion webhook was moved in apiextensions.k8s.io/v1
+			if b := fieldCABundle("spec", "conversion", "webhook", "clientConfig", "caBundle")(obj); len(b) > 0 {
+				return b
+			}
+			return fieldCABundle("spec", "conversion", "webhookClientConfig", "caBundle")(obj)
+		},
+	},
+}
+
+// collectCABundles reads CA certifications embedded in webhooks, apiservices and conversion webhooks of CRDs
+func collectCABundles(client dynamic.Interface) []serverCertification {
+	certs := []serverCertification{}
+	for _, source := range caBundleSources {
+		certs = append(certs, source.collect(client)...)
+	}
+	return certs
+}
+
+func (s caBundleSource) collect(client dynamic.Interface) []serverCertification {
+	var lastErr error
+	for _, resource := range s.resources {
+		list, err := client.Resource(resource).List(meta_v1.ListOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				lastErr = err
+			}
+			continue
+		}
+
+		certs := []serverCertification{}
+		for i := range list.Items {
+			certs = append(certs, caBundleCertifications(s.entryType, s.bundles(&list.Items[i]))...)
+		}
+		return certs
+	}
+
+	// the api is not served at all in this cluster
+	if lastErr == nil {
+		return nil
+	}
+	return []serverCertification{{
+		Entry: Entry{
+			Type: s.entryType,
+			Name: "Error",
+		},
+		Warning: lastErr.Error(),
+	}}
+}
+
+// caBundleCertifications decodes caBundles and makes certifications of them
+func caBundleCertifications(entryType string, bundles map[string]string) []serverCertification {
+	paths := make([]string, 0, len(bundles))
+	for path := range bundles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	certs := []serverCertification{}
+	for _, path := range paths {
+		cert, err := base64.StdEncoding.DecodeString(bundles[path])
+		certs = append(certs, newCertifications(entryType, "", caBundleName, path, string(cert), err)...)
+	}
+	return certs
+}
+
+// webhookCABundles returns caBundles of every webhook by `configuration/webhook`
+func webhookCABundles(obj *unstructured.Unstructured) map[string]string {
+	bundles := map[string]string{}
+	webhooks, _, _ := unstructured.NestedSlice(obj.Object, "webhooks")
+	for _, w := range webhooks {
+		webhook, ok := w.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(webhook, "name")
+		bundle, _, _ := unstructured.NestedString(webhook, "clientConfig", "caBundle")
+		if bundle != "" {
+			bundles[fmt.Sprintf("%s/%s", obj.GetName(), name)] = bundle
+		}
+	}
+	return bundles
+}
+
+// fieldCABundle returns a func reading a caBundle at fields of an object by its name
+func fieldCABundle(fields ...string) func(obj *unstructured.Unstructured) map[string]string {
+	return func(obj *unstructured.Unstructured) map[string]string {
+		bundles := map[string]string{}
+		bundle, _, _ := unstructured.NestedString(obj.Object, fields...)
+		if bundle != "" {
+			bundles[obj.GetName()] = bundle
+		}
+		return bundles
+	}
+}
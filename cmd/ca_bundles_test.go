@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestWebhookCABundles(t *testing.T) {
+	bundle := base64.StdEncoding.EncodeToString([]byte(newTestCert(t, "webhook", time.Now(), time.Now().Add(time.Hour))))
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "config"},
+		"webhooks": []interface{}{
+			map[string]interface{}{"name": "a.example.com", "clientConfig": map[string]interface{}{"caBundle": bundle}},
+			map[string]interface{}{"name": "b.example.com", "clientConfig": map[string]interface{}{"url": "https://example.com"}},
+			map[string]interface{}{"name": "c.example.com", "clientConfig": map[string]interface{}{"caBundle": "!!"}},
+		},
+	}}
+
+	certs := caBundleCertifications("mutatingwebhook", webhookCABundles(obj))
+
+	assert.Equal(t, 2, len(certs))
+	assert.Equal(t, "config/a.example.com", certs[0].Entry.Path)
+	assert.Equal(t, "caBundle", certs[0].Entry.Name)
+	assert.Equal(t, "", certs[0].Warning)
+	assert.Equal(t, "config/c.example.com", certs[1].Entry.Path)
+	assert.NotEqual(t, "", certs[1].Warning)
+}
+
+func TestCRDCABundles(t *testing.T) {
+	var crd caBundleSource
+	for _, s := range caBundleSources {
+		if s.entryType == "crd" {
+			crd = s
+		}
+	}
+
+	v1 := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "foos.example.com"},
+		"spec": map[string]interface{}{"conversion": map[string]interface{}{
+			"webhook": map[string]interface{}{"clientConfig": map[string]interface{}{"caBundle": "djE="}},
+		}},
+	}}
+	v1beta1 := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "bars.example.com"},
+		"spec": map[string]interface{}{"conversion": map[string]interface{}{
+			"webhookClientConfig": map[string]interface{}{"caBundle": "djFiZXRhMQ=="},
+		}},
+	}}
+	none := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "bazs.example.com"},
+	}}
+
+	assert.Equal(t, map[string]string{"foos.example.com": "djE="}, crd.bundles(v1))
+	assert.Equal(t, map[string]string{"bars.example.com": "djFiZXRhMQ=="}, crd.bundles(v1beta1))
+	assert.Equal(t, map[string]string{}, crd.bundles(none))
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// caRule tells which CA entry a certification is verified against
+type caRule struct {
+	entryType string
+	name      string
+	// the CA can be found on any node, e.g. apiserver's CA for kubelets
+	anyNode bool
+}
+
+// chainRules maps `type/name` of certifications to the CA their component is configured with.
+// Certifications of kubeconfigs carry the CA of the kubeconfig instead.
+var chainRules = map[string]caRule{
+	"apiserver/kubelet-client-certificate": {entryType: "apiserver", name: "client-ca-file"},
+	"apiserver/proxy-client-cert-file":     {entryType: "apiserver", name: "requestheader-client-ca-file"},
+	"apiserver/etcd-certfile":              {entryType: "apiserver", name: "etcd-cafile"},
+	"etcd/cert-file":                       {entryType: "etcd", name: "trusted-ca-file"},
+	"etcd/peer-cert-file":                  {entryType: "etcd", name: "peer-trusted-ca-file"},
+	"etcd/" + healthcheckClientName:        {entryType: "etcd", name: "trusted-ca-file"},
+	"kubelet/server-cert":                  {entryType: "apiserver", name: kubeletCAFlag, anyNode: true},
+	"kubelet/client-cert":                  {entryType: "apiserver", name: "client-ca-file", anyNode: true},
+}
+
+// verifyChains verifies every certification against its configured CA and adds warnings of findings.
+// apiserverCA is the CA that clients trust for the serving certification of apiserver, e.g. of the kubeconfig in use.
+func verifyChains(certs []serverCertification, apiserverCA []*x509.Certificate, now time.Time) {
+	cas := map[string][]*x509.Certificate{}
+	for _, v := range certs {
+		if v.Entry.Position == 0 && len(v.certs) > 0 {
+			cas[caKey(v.Entry.Type, v.Entry.Node, v.Entry.Name)] = v.certs
+			if _, ok := cas[caKey(v.Entry.Type, "", v.Entry.Name)]; !ok {
+				cas[caKey(v.Entry.Type, "", v.Entry.Name)] = v.certs
+			}
+		}
+	}
+
+	for i := range certs {
+		v := &certs[i]
+		if v.Entry.Position != 0 || len(v.certs) == 0 {
+			continue
+		}
+
+		roots, caName := v.roots, v.caName
+		if len(roots) == 0 {
+			if v.Entry.Type == "apiserver" && v.Entry.Name == tlsCertFlag {
+				roots, caName = apiserverCA, "kubeconfig certificate-authority"
+			} else if rule, ok := chainRules[v.Entry.Type+"/"+v.Entry.Name]; ok {
+				node := v.Entry.Node
+				if rule.anyNode {
+					node = ""
+				}
+				roots, caName = cas[caKey(rule.entryType, node, rule.name)], rule.name
+			}
+		}
+		if len(roots) == 0 {
+			continue
+		}
+
+		for _, w := range verifyChain(v.certs, roots, caName, now) {
+			v.addWarning(w)
+		}
+	}
+}
+
+func caKey(entryType string, node string, name string) string {
+	return strings.Join([]string{entryType, node, name}, "/")
+}
+
+// verifyChain builds a chain from the first certificate of certs through the rest of certs to roots
+// and returns findings about the chain at now
+func verifyChain(certs []*x509.Certificate, roots []*x509.Certificate, caName string, now time.Time) []string {
+	leaf := certs[0]
+	rootPool := x509.NewCertPool()
+	for _, c := range roots {
+		rootPool.AddCert(c)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	// expiry of the certification itself is already reported by its status
+	at := now
+	if at.After(leaf.NotAfter) {
+		at = leaf.NotAfter
+	}
+	if at.Before(leaf.NotBefore) {
+		at = leaf.NotBefore
+	}
+
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         rootPool,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		for _, ca := range append(append([]*x509.Certificate{}, certs[1:]...), roots...) {
+			if leaf.CheckSignatureFrom(ca) == nil && ca.NotAfter.Before(at) {
+				return []string{fmt.Sprintf("issuer CA %q of %s expired at %s", ca.Subject.CommonName, caName, ca.NotAfter)}
+			}
+		}
+		return []string{fmt.Sprintf("chain does not verify against %s: %s", caName, err)}
+	}
+
+	warnings := []string{}
+	for _, ca := range chains[0][1:] {
+		if ca.NotAfter.Before(now) {
+			warnings = append(warnings, fmt.Sprintf("issuer CA %q of %s expired at %s", ca.Subject.CommonName, caName, ca.NotAfter))
+		} else if ca.NotAfter.Before(leaf.NotAfter) {
+			warnings = append(warnings, fmt.Sprintf("issuer CA %q of %s expires at %s before the certificate", ca.Subject.CommonName, caName, ca.NotAfter))
+		}
+	}
+	return warnings
+}
+
+// addWarning appends w to the warnings of the certification
+func (c *serverCertification) addWarning(w string) {
+	if c.Warning == "" {
+		c.Warning = w
+		return
+	}
+	c.Warning = c.Warning + "; " + w
+}
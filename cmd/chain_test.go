@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLeaf(t *testing.T, notAfter time.Time, parent *testCert) *testCert {
+	return newTestCertificate(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "leaf"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  notAfter,
+	}, parent)
+}
+
+func TestVerifyChain(t *testing.T) {
+	now := time.Now()
+	ca := newTestCA(t, "ca", now.Add(10*365*24*time.Hour), nil)
+	leaf := newTestLeaf(t, now.Add(365*24*time.Hour), ca)
+
+	assert.Empty(t, verifyChain([]*x509.Certificate{leaf.cert}, []*x509.Certificate{ca.cert}, "client-ca-file", now))
+}
+
+func TestVerifyChainWithIntermediate(t *testing.T) {
+	now := time.Now()
+	root := newTestCA(t, "root", now.Add(10*365*24*time.Hour), nil)
+	intermediate := newTestCA(t, "intermediate", now.Add(5*365*24*time.Hour), root)
+	leaf := newTestLeaf(t, now.Add(365*24*time.Hour), intermediate)
+
+	assert.Empty(t, verifyChain([]*x509.Certificate{leaf.cert, intermediate.cert}, []*x509.Certificate{root.cert}, "client-ca-file", now))
+}
+
+func TestVerifyChainWithOtherCA(t *testing.T) {
+	now := time.Now()
+	ca := newTestCA(t, "ca", now.Add(10*365*24*time.Hour), nil)
+	other := newTestCA(t, "other", now.Add(10*365*24*time.Hour), nil)
+	leaf := newTestLeaf(t, now.Add(365*24*time.Hour), other)
+
+	warnings := verifyChain([]*x509.Certificate{leaf.cert}, []*x509.Certificate{ca.cert}, "client-ca-file", now)
+	if assert.Len(t, warnings, 1) {
+		assert.Contains(t, warnings[0], "chain does not verify against client-ca-file")
+	}
+}
+
+func TestVerifyChainWithExpiredCA(t *testing.T) {
+	now := time.Now()
+	ca := newTestCA(t, "ca", now.Add(24*time.Hour), nil)
+	leaf := newTestLeaf(t, now.Add(365*24*time.Hour), ca)
+
+	warnings := verifyChain([]*x509.Certificate{leaf.cert}, []*x509.Certificate{ca.cert}, "client-ca-file", now.Add(48*time.Hour))
+	if assert.Len(t, warnings, 1) {
+		assert.Contains(t, warnings[0], `issuer CA "ca" of client-ca-file expired`)
+	}
+}
+
+func TestVerifyChainWithCAExpiringBeforeLeaf(t *testing.T) {
+	now := time.Now()
+	ca := newTestCA(t, "ca", now.Add(30*24*time.Hour), nil)
+	leaf := newTestLeaf(t, now.Add(365*24*time.Hour), ca)
+
+	warnings := verifyChain([]*x509.Certificate{leaf.cert}, []*x509.Certificate{ca.cert}, "client-ca-file", now)
+	if assert.Len(t, warnings, 1) {
+		assert.Contains(t, warnings[0], "before the certificate")
+	}
+}
+
+func TestVerifyChains(t *testing.T) {
+	now := time.Now()
+	ca := newTestCA(t, "ca", now.Add(10*365*24*time.Hour), nil)
+	other := newTestCA(t, "other", now.Add(10*365*24*time.Hour), nil)
+
+	certs := append(newCertifications("etcd", "node1", "trusted-ca-file", "/etc/kubernetes/pki/etcd/ca.crt", ca.pem, nil),
+		newCertifications("etcd", "node1", "cert-file", "/etc/kubernetes/pki/etcd/server.crt", newTestLeaf(t, now.Add(365*24*time.Hour), ca).pem, nil)...)
+	certs = append(certs,
+		newCertifications("etcd", "node1", "peer-cert-file", "/etc/kubernetes/pki/etcd/peer.crt", newTestLeaf(t, now.Add(365*24*time.Hour), other).pem, nil)...)
+	certs = append(certs,
+		newCertifications("etcd", "node1", "peer-trusted-ca-file", "/etc/kubernetes/pki/etcd/ca.crt", ca.pem, nil)...)
+	verifyChains(certs, nil, now)
+
+	assert.Empty(t, certs[0].Warning)
+	assert.Empty(t, certs[1].Warning)
+	assert.Contains(t, certs[2].Warning, "chain does not verify against peer-trusted-ca-file")
+}
@@ -4,19 +4,19 @@ import (
 	"bytes"
 	"crypto/x509"
 	"encoding/json"
-	"encoding/pem"
 	"fmt"
-	"os"
-	"sort"
+	"io"
+	"io/ioutil"
+	"path"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/common/log"
-	"github.com/spf13/cast"
 	"github.com/spf13/cobra"
 	pb "gopkg.in/cheggaaa/pb.v1"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes/scheme"
 	appsV1Client "k8s.io/client-go/kubernetes/typed/apps/v1"
 	coreV1Client "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -27,13 +27,10 @@ import (
 	// ref:https://github.com/kubernetes/client-go/issues/242
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 
-	appv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
-
-	"github.com/olekukonko/tablewriter"
 )
 
 const (
@@ -42,6 +39,11 @@ const (
 
 	kubeletCAFlag = "kubelet-certificate-authority"
 
+	// kubeadm's etcd liveness probe uses this certification with etcdctl
+	healthcheckClientName = "healthcheck-client"
+	etcdctlCertFlag       = "--cert="
+	etcdctlKeyFlag        = "--key="
+
 	name              = "krawler"
 	imageName         = "leoh0/krawler"
 	etcKubernetesPath = "/etc/kubernetes/"
@@ -55,15 +57,53 @@ const (
 
 var (
 	expirationExample = `
-	# view expiration days of certifications about control plane (e.g. apiserver, controller-manager, scheduler)
+	# view expiration days of certifications about control plane (e.g. apiserver, etcd, controller-manager, scheduler)
 	%[1]s check-cert
 
 	# view expiration days of certifications about control plane and also kubelets by installing crawling daemon-set
 	%[1]s check-cert --also-check-kubelet
+
+	# print the certifications as json for automation
+	%[1]s check-cert -o json
+
+	# print only node and remaining days of every certification
+	%[1]s check-cert -o custom-columns=NODE:.entry.node,DAYS:.entry.days
+	%[1]s check-cert -o jsonpath='{range .items[*]}{.entry.node}{"\t"}{.entry.days}{"\n"}{end}'
+
+	# view expiration days of certifications in TLS secrets of the ingress-nginx namespace too
+	%[1]s check-cert --include-secrets --secret-namespaces ingress-nginx
+
+	# view expiration days of caBundles in webhooks, apiservices and CRD conversion webhooks too
+	%[1]s check-cert --include-ca-bundles
+
+	# view subject, issuer, SANs and fingerprints of certifications
+	%[1]s check-cert -o wide
+
+	# exit with 1 when a certification expires within 30 days and with 2 within 7 days
+	%[1]s check-cert --warn-days 30 --critical-days 7
+
+	# view which certifications will be expired at the maintenance window or in 90 days
+	%[1]s check-cert --at 2020-06-01T00:00:00Z
+	%[1]s check-cert --in 2160h
+
+	# view only kubelet certifications expiring within 30 days, the earliest first
+	%[1]s check-cert --also-check-kubelet --type kubelet --expiring-within 720h --sort-by due
+
+	# view counts and days of kubelet certifications over nodes by type and name
+	%[1]s check-cert --also-check-kubelet --summary
+
+	# also warn about certifications valid for longer than 398 days
+	%[1]s check-cert --max-validity-days 398
 `
 
 	certOptions = []string{"etcd-certfile", "tls-cert-file", "kubelet-client-certificate", "proxy-client-cert-file"}
 
+	etcdCertOptions = []string{"cert-file", "peer-cert-file"}
+
+	// CA certifications are reported as separated entries because they expire on their own
+	caOptions     = []string{"client-ca-file", "requestheader-client-ca-file", "etcd-cafile", kubeletCAFlag}
+	etcdCAOptions = []string{"trusted-ca-file", "peer-trusted-ca-file"}
+
 	matchLabel = map[string]string{"app": name}
 	max        = intstr.FromString("100%")
 
@@ -75,23 +115,58 @@ var (
 )
 
 type serverCertification struct {
-	Entry   Entry
-	Warning string
+	Entry   Entry  `json:"entry"`
+	Status  string `json:"status"`
+	Warning string `json:"warning"`
+	// RemainingSeconds is the precise time until the due, negative if it is expired
+	RemainingSeconds int64 `json:"remainingSeconds,omitempty"`
+
+	// parsed certificates of the file and the CA it has to be verified against if known while collecting
+	certs  []*x509.Certificate
+	roots  []*x509.Certificate
+	caName string
+	// clock is the evaluation time by the clock of the node the certification is read on if it is known
+	clock time.Time
 }
 
 // ExpirationOptions provides information
 type ExpirationOptions struct {
 	configFlags *genericclioptions.ConfigFlags
+	printFlags  *genericclioptions.PrintFlags
 	genericclioptions.IOStreams
 
 	checkKubelet bool
+	progress     bool
+	details      bool
+
+	includeSecrets   bool
+	secretNamespaces []string
+	includeCABundles bool
+
+	warnDays     int
+	criticalDays int
+
+	maxValidityDays int
+	maxClockSkew    time.Duration
+
+	filter  certificationFilter
+	sortBy  string
+	summary bool
+
+	// at or in moves the time certifications are evaluated at
+	at     string
+	in     time.Duration
+	atTime time.Time
 }
 
 // NewExpirationOptions provides an instance of ExpirationOptions with default values
 func NewExpirationOptions(streams genericclioptions.IOStreams) *ExpirationOptions {
 	return &ExpirationOptions{
 		configFlags:  genericclioptions.NewConfigFlags(true),
+		printFlags:   newPrintFlags(),
 		checkKubelet: false,
+		progress:     true,
+		maxClockSkew: defaultMaxClockSkew,
 		IOStreams:    streams,
 	}
 }
@@ -106,6 +181,10 @@ func NewCmdExpiration(streams genericclioptions.IOStreams) *cobra.Command {
 		Example:      fmt.Sprintf(expirationExample, "kubectl"),
 		SilenceUsage: true,
 		RunE: func(c *cobra.Command, args []string) error {
+			o.setOutputFlagSpecified(c)
+			if err := o.Validate(); err != nil {
+				return err
+			}
 			if err := o.Run(c); err != nil {
 				return err
 			}
@@ -113,12 +192,48 @@ func NewCmdExpiration(streams genericclioptions.IOStreams) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().BoolVar(&o.checkKubelet, "also-check-kubelet", false, "if true, also check kubelet certification")
-	o.configFlags.AddFlags(cmd.Flags())
+	cmd.PersistentFlags().BoolVar(&o.checkKubelet, "also-check-kubelet", false, "if true, also check kubelet certification")
+	cmd.PersistentFlags().BoolVar(&o.includeSecrets, "include-secrets", false, "if true, also check certifications in secrets of type kubernetes.io/tls")
+	cmd.PersistentFlags().StringSliceVar(&o.secretNamespaces, "secret-namespaces", nil, "namespaces to look for TLS secrets in, all namespaces if empty")
+	cmd.PersistentFlags().BoolVar(&o.includeCABundles, "include-ca-bundles", false, "if true, also check caBundles of webhooks, apiservices and CRD conversion webhooks")
+	cmd.Flags().DurationVar(&o.maxClockSkew, "max-clock-skew", o.maxClockSkew, "warn about kubelet certifications of nodes whose clock deviates from this client by more than this")
+	o.addReportFlags(cmd)
+	o.configFlags.AddFlags(cmd.PersistentFlags())
+
+	cmd.AddCommand(NewCmdServe(o))
+	cmd.AddCommand(NewCmdManifests(o))
+	cmd.AddCommand(NewCmdLocal(o))
+	cmd.AddCommand(NewCmdArchive(o))
+	cmd.AddCommand(NewCmdKubeconfig(o))
 
 	return cmd
 }
 
+// addReportFlags adds flags about evaluating, selecting and printing certifications to a command which reports them
+func (o *ExpirationOptions) addReportFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&o.details, "details", false, "if true, show subject, issuer, SANs, serial, key, signature, not before and fingerprint of certifications. Same as -o wide for the table")
+	cmd.Flags().IntVar(&o.warnDays, "warn-days", 0, "certifications expiring in less than this many days are WARNING and exit with 1. Without thresholds the exit code is 0 whatever the status is")
+	cmd.Flags().IntVar(&o.criticalDays, "critical-days", 0, "certifications expiring in less than this many days are CRITICAL and exit with 2")
+	cmd.Flags().IntVar(&o.maxValidityDays, "max-validity-days", 0, "warn about certifications other than CA valid for longer than this many days. 0 means no limit")
+	cmd.Flags().StringVar(&o.at, "at", "", "evaluate certifications at this RFC3339 time instead of now, e.g. 2020-01-02T15:04:05Z")
+	cmd.Flags().DurationVar(&o.in, "in", 0, "evaluate certifications at this duration from now instead of now, e.g. 720h")
+	cmd.Flags().StringSliceVar(&o.filter.types, "type", nil, "show only certifications of these types, e.g. apiserver,kubelet. Glob patterns are allowed")
+	cmd.Flags().StringSliceVar(&o.filter.nodes, "node", nil, "show only certifications of these nodes. Glob patterns are allowed")
+	cmd.Flags().StringSliceVar(&o.filter.names, "name", nil, "show only certifications of these names, e.g. client-cert. Glob patterns are allowed")
+	cmd.Flags().DurationVar(&o.filter.expiringWithin, "expiring-within", 0, "show only certifications expiring within this duration, e.g. 720h, and ones which could not be collected")
+	cmd.Flags().StringVar(&o.sortBy, "sort-by", defaultSort, fmt.Sprintf("sort certifications by one of: %s. By type, node and name if empty", strings.Join(sortOrders, "|")))
+	cmd.Flags().BoolVar(&o.summary, "summary", false, "if true, show counts and min, median and max days of certifications by type and name instead of every certification")
+	cmd.Flags().StringVarP(o.printFlags.OutputFormat, "output", "o", "", fmt.Sprintf("Output format. One of: %s.", strings.Join(o.outputFormats(), "|")))
+	o.printFlags.TemplatePrinterFlags.AddFlags(cmd)
+}
+
+// setOutputFlagSpecified tells the printers whether --output is given to the running command
+func (o *ExpirationOptions) setOutputFlagSpecified(cmd *cobra.Command) {
+	o.printFlags.OutputFlagSpecified = func() bool {
+		return cmd.Flag("output").Changed
+	}
+}
+
 func getPods(coreclient *coreV1Client.CoreV1Client, namespace string, label string) (*corev1.PodList, error) {
 	listOption := meta_v1.ListOptions{
 		LabelSelector: label,
@@ -132,166 +247,384 @@ func getPods(coreclient *coreV1Client.CoreV1Client, namespace string, label stri
 	return pods, nil
 }
 
+// podCommand returns the command and args of the main container
+func podCommand(p *corev1.Pod) []string {
+	c := p.Spec.Containers[0]
+	return append(append([]string{}, c.Command...), c.Args...)
+}
+
+// getFlags takes a command and returns values of its `--flag=value` options
+func getFlags(command []string) map[string]string {
+	flags := map[string]string{}
+	for _, c := range command {
+		// only for options
+		if strings.HasPrefix(c, "--") {
+			s := strings.SplitN(c[2:], "=", 2)
+			if len(s) == 2 {
+				flags[s[0]] = s[1]
+			} else {
+				flags[s[0]] = ""
+			}
+		}
+	}
+	return flags
+}
+
+// fileReader reads a file on the node of a pod
+type fileReader interface {
+	readFile(p *corev1.Pod, path string) (string, error)
+}
+
+// apiserverCertifications reads certifications and CAs given by flags of an apiserver pod
+func apiserverCertifications(r fileReader, p *corev1.Pod) []serverCertification {
+	flags := getFlags(podCommand(p))
+	return append(readFlagCertifications(r, p, "apiserver", flags, certOptions),
+		readFlagCertifications(r, p, "apiserver", flags, caOptions)...)
+}
+
+// etcdCertifications reads certifications and CAs given by flags of an etcd pod and the one of its liveness probe
+func etcdCertifications(r fileReader, p *corev1.Pod) []serverCertification {
+	flags := getFlags(podCommand(p))
+	certs := append(readFlagCertifications(r, p, "etcd", flags, etcdCertOptions),
+		readFlagCertifications(r, p, "etcd", flags, etcdCAOptions)...)
+	if path := healthcheckCertPath(p); path != "" {
+		cert, err := r.readFile(p, path)
+		// the certification next to the CA is only a convention of kubeadm, it is not there on other clusters
+		if err != nil && livenessProbeFlag(p, etcdctlCertFlag) == "" {
+			return certs
+		}
+		c := newCertifications("etcd", p.Spec.NodeName, healthcheckClientName, path, cert, err)
+		if keyPath := healthcheckKeyPath(p); keyPath != "" {
+			key, err := r.readFile(p, keyPath)
+			checkKeyPair(c, cert, key, keyPath, err)
+		}
+		certs = append(certs, c...)
+	}
+	return certs
+}
+
+// componentKubeconfigCertifications reads the client certification in the kubeconfig given by --kubeconfig of the pod
+func componentKubeconfigCertifications(r fileReader, p *corev1.Pod, entryType string) []serverCertification {
+	path := getFlags(podCommand(p))[kubeConfigFlag]
+	if path == "" {
+		return nil
+	}
+	return readKubeconfigCertifications(r, p, entryType, path)
+}
+
+// readFlagCertifications reads certifications given by options among flags of the pod
+func readFlagCertifications(r fileReader, p *corev1.Pod, entryType string, flags map[string]string, options []string) []serverCertification {
+	certs := []serverCertification{}
+	for _, co := range options {
+		path, ok := flags[co]
+		if !ok || path == "" {
+			continue
+		}
+		cert, err := r.readFile(p, path)
+		c := newCertifications(entryType, p.Spec.NodeName, co, path, cert, err)
+		if keyPath := flags[keyOptions[co]]; keyPath != "" {
+			key, err := r.readFile(p, keyPath)
+			checkKeyPair(c, cert, key, keyPath, err)
+		}
+		certs = append(certs, c...)
+	}
+	return certs
+}
+
+// readKubeconfigCertifications reads the client certification of the current context in the kubeconfig of the pod
+func readKubeconfigCertifications(r fileReader, p *corev1.Pod, entryType string, kubeconfigPath string) []serverCertification {
+	errorResult := func(err error) []serverCertification {
+		return []serverCertification{{
+			Entry: Entry{
+				Type: entryType,
+				Node: p.Spec.NodeName,
+				Name: "client-cert",
+				Path: "-",
+				Days: 0,
+				Due:  time.Time{},
+			},
+			Warning: err.Error(),
+		}}
+	}
+
+	kubeconfig, err := r.readFile(p, kubeconfigPath)
+	if err != nil {
+		return errorResult(err)
+	}
+	cfg, err := clientcmd.NewClientConfigFromBytes([]byte(kubeconfig))
+	if err != nil {
+		return errorResult(err)
+	}
+	rawConfig, err := cfg.RawConfig()
+	if err != nil {
+		return errorResult(err)
+	}
+
+	currentContext, ok := rawConfig.Contexts[rawConfig.CurrentContext]
+	if !ok {
+		return errorResult(fmt.Errorf("current context %q is not in %s", rawConfig.CurrentContext, kubeconfigPath))
+	}
+	u, ok := rawConfig.AuthInfos[currentContext.AuthInfo]
+	if !ok {
+		return errorResult(fmt.Errorf("user %q is not in %s", currentContext.AuthInfo, kubeconfigPath))
+	}
+
+	var (
+		certs []serverCertification
+		cert  string
+	)
+	if string(u.ClientCertificateData) != "" {
+		cert = string(u.ClientCertificateData)
+		certs = newCertifications(entryType, p.Spec.NodeName, "client-cert", kubeconfigPath, cert, nil)
+	} else if string(u.ClientCertificate) != "" {
+		cert, err = r.readFile(p, u.ClientCertificate)
+		certs = newCertifications(entryType, p.Spec.NodeName, "client-cert", u.ClientCertificate, cert, err)
+	} else {
+		return errorResult(fmt.Errorf("user %q has no client certificate in %s", currentContext.AuthInfo, kubeconfigPath))
+	}
+
+	if string(u.ClientKeyData) != "" {
+		checkKeyPair(certs, cert, string(u.ClientKeyData), "client-key-data of "+kubeconfigPath, nil)
+	} else if u.ClientKey != "" {
+		key, err := r.readFile(p, u.ClientKey)
+		checkKeyPair(certs, cert, key, u.ClientKey, err)
+	}
+
+	// the client certification is verified against the CA of the kubeconfig
+	if cluster, ok := rawConfig.Clusters[currentContext.Cluster]; ok {
+		if len(cluster.CertificateAuthorityData) > 0 {
+			certs[0].roots, _ = GetCertificatesFromPEM(string(cluster.CertificateAuthorityData))
+			certs[0].caName = "certificate-authority-data of " + kubeconfigPath
+		} else if cluster.CertificateAuthority != "" {
+			if ca, err := r.readFile(p, cluster.CertificateAuthority); err == nil {
+				certs[0].roots, _ = GetCertificatesFromPEM(ca)
+				certs[0].caName = cluster.CertificateAuthority
+			}
+		}
+	}
+	return certs
+}
+
+// healthcheckCertPath finds the client certification used by etcdctl in the liveness probe of etcd.
+// kubeadm 1.16 and later probe etcd by http, then it is healthcheck-client.crt next to --trusted-ca-file.
+func healthcheckCertPath(p *corev1.Pod) string {
+	if path := livenessProbeFlag(p, etcdctlCertFlag); path != "" {
+		return path
+	}
+	return healthcheckDefaultPath(p, ".crt")
+}
+
+// healthcheckKeyPath finds the private key of the client certification used by etcdctl in the liveness probe of etcd,
+// or healthcheck-client.key next to --trusted-ca-file
+func healthcheckKeyPath(p *corev1.Pod) string {
+	if path := livenessProbeFlag(p, etcdctlKeyFlag); path != "" {
+		return path
+	}
+	return healthcheckDefaultPath(p, ".key")
+}
+
+// healthcheckDefaultPath is the file of healthcheck-client with ext in the directory of --trusted-ca-file by kubeadm
+func healthcheckDefaultPath(p *corev1.Pod, ext string) string {
+	ca := getFlags(podCommand(p))["trusted-ca-file"]
+	if ca == "" {
+		return ""
+	}
+	return path.Join(path.Dir(ca), healthcheckClientName+ext)
+}
+
+func livenessProbeFlag(p *corev1.Pod, flag string) string {
+	probe := p.Spec.Containers[0].LivenessProbe
+	if probe == nil || probe.Exec == nil {
+		return ""
+	}
+	for _, c := range probe.Exec.Command {
+		for _, f := range strings.Fields(c) {
+			if strings.HasPrefix(f, flag) {
+				return strings.TrimPrefix(f, flag)
+			}
+		}
+	}
+	return ""
+}
+
+// evaluationTime is the time certifications are evaluated at, now unless --at or --in is given
+func (o *ExpirationOptions) evaluationTime() time.Time {
+	if !o.atTime.IsZero() {
+		return o.atTime
+	}
+	return time.Now().Add(o.in)
+}
+
+// apiserverCA returns the CA which the kubeconfig in use trusts for apiserver
+func (o *ExpirationOptions) apiserverCA() []*x509.Certificate {
+	ca := clientConfig.TLSClientConfig.CAData
+	if len(ca) == 0 && clientConfig.TLSClientConfig.CAFile != "" {
+		ca, _ = ioutil.ReadFile(clientConfig.TLSClientConfig.CAFile)
+	}
+	if len(ca) == 0 {
+		return nil
+	}
+	certs, _ := GetCertificatesFromPEM(string(ca))
+	return certs
+}
+
 func isJSON(s string) (*Output, bool) {
 	var e Output
 	val := json.Unmarshal([]byte(s), &e)
 	return &e, val == nil
 }
 
+// Validate ensures that all required arguments and flag values are provided
+func (o *ExpirationOptions) Validate() error {
+	if o.warnDays < 0 || o.criticalDays < 0 {
+		return fmt.Errorf("--warn-days and --critical-days must not be negative")
+	}
+	if o.maxValidityDays < 0 {
+		return fmt.Errorf("--max-validity-days must not be negative")
+	}
+	if o.maxClockSkew < 0 {
+		return fmt.Errorf("--max-clock-skew must not be negative")
+	}
+	if err := o.filter.validate(); err != nil {
+		return err
+	}
+	if err := validateSortBy(o.sortBy); err != nil {
+		return err
+	}
+	if o.at != "" && o.in != 0 {
+		return fmt.Errorf("only one of --at and --in can be given")
+	}
+	if o.at != "" {
+		t, err := time.Parse(time.RFC3339, o.at)
+		if err != nil {
+			return fmt.Errorf("--at must be RFC3339, e.g. 2020-01-02T15:04:05Z: %s", err)
+		}
+		o.atTime = t
+	}
+	return o.validateOutput()
+}
+
 // Run gather all information
 func (o *ExpirationOptions) Run(cmd *cobra.Command) error {
-	var err error
-	clientConfig, err = o.configFlags.ToRESTConfig()
+	serverCertifications, err := o.collect()
 	if err != nil {
 		return err
 	}
-	coreclient, err := coreV1Client.NewForConfig(clientConfig)
-	if err != nil {
+
+	return o.report(serverCertifications)
+}
+
+// audit adds warnings of findings about chains, cryptography and validity to certifications
+func (o *ExpirationOptions) audit(certs []serverCertification, apiserverCA []*x509.Certificate) {
+	verifyChains(certs, apiserverCA, o.evaluationTime())
+	auditCrypto(certs, o.maxValidityDays)
+	auditNotBefore(certs, o.evaluationTime())
+}
+
+// report classifies, selects and prints certifications and returns the result of the check
+func (o *ExpirationOptions) report(serverCertifications []serverCertification) error {
+	at := o.evaluationTime()
+	classify(serverCertifications, o.warnDays, o.criticalDays, at)
+	serverCertifications = o.filter.filter(serverCertifications, at)
+	sortCertifications(serverCertifications, o.sortBy)
+	if err := o.printCertifications(serverCertifications); err != nil {
 		return err
 	}
 
-	if o.checkKubelet {
-		daemonSet := &appv1.DaemonSet{
-			ObjectMeta: meta_v1.ObjectMeta{
-				Name: name,
-			},
-			Spec: appv1.DaemonSetSpec{
-				UpdateStrategy: appv1.DaemonSetUpdateStrategy{
-					Type: appv1.RollingUpdateDaemonSetStrategyType,
-					RollingUpdate: &appv1.RollingUpdateDaemonSet{
-						MaxUnavailable: &max,
-					},
-				},
-				Selector: &meta_v1.LabelSelector{
-					MatchLabels: matchLabel,
-				},
-				Template: corev1.PodTemplateSpec{
-					ObjectMeta: meta_v1.ObjectMeta{
-						Labels: matchLabel,
-					},
-					Spec: corev1.PodSpec{
-						HostPID:     true,
-						HostNetwork: true,
-						Containers: []corev1.Container{{
-							Name: name,
-							Env: []corev1.EnvVar{
-								{
-									Name: "NODENAME",
-									ValueFrom: &corev1.EnvVarSource{
-										FieldRef: &corev1.ObjectFieldSelector{
-											FieldPath: "spec.nodeName",
-										},
-									},
-								},
-							},
-							Image:           imageName,
-							ImagePullPolicy: corev1.PullAlways,
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      etcKubernetesName,
-									MountPath: etcKubernetesPath,
-								}, {
-									Name:      varLibKubeletName,
-									MountPath: varLibKubeletPath,
-								}, {
-									Name:      tmpProcName,
-									MountPath: tmpProcPath,
-								},
-							},
-						}},
-						RestartPolicy: corev1.RestartPolicyAlways,
-						Tolerations: []corev1.Toleration{{
-							Operator: corev1.TolerationOpExists,
-						}},
-						Volumes: []corev1.Volume{
-							{
-								Name: etcKubernetesName,
-								VolumeSource: corev1.VolumeSource{
-									HostPath: &corev1.HostPathVolumeSource{
-										Type: &hostPathType,
-										Path: etcKubernetesPath,
-									},
-								},
-							}, {
-								Name: varLibKubeletName,
-								VolumeSource: corev1.VolumeSource{
-									HostPath: &corev1.HostPathVolumeSource{
-										Type: &hostPathType,
-										Path: varLibKubeletPath,
-									},
-								},
-							}, {
-								Name: tmpProcName,
-								VolumeSource: corev1.VolumeSource{
-									HostPath: &corev1.HostPathVolumeSource{
-										Type: &hostPathType,
-										Path: realProcPath,
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		}
+	// existing scripts expect 0 unless nagios semantics are asked by thresholds
+	if !o.thresholdsSet() {
+		return nil
+	}
+	return checkResult(serverCertifications)
+}
 
-		appClient, err := appsV1Client.NewForConfig(clientConfig)
-		if err != nil {
-			return err
-		}
+// thresholdsSet tells whether --warn-days or --critical-days is given to exit by the worst status
+func (o *ExpirationOptions) thresholdsSet() bool {
+	return o.warnDays > 0 || o.criticalDays > 0
+}
 
-		_, err = appClient.DaemonSets(defaultNamespace).Create(daemonSet)
+// newKrawler makes clients of the cluster by the config flags and a krawler with them
+func (o *ExpirationOptions) newKrawler() (*krawler, error) {
+	var err error
+	clientConfig, err = o.configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	coreclient, err := coreV1Client.NewForConfig(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	appClient, err := appsV1Client.NewForConfig(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newKrawler(coreclient, appClient, o.ErrOut), nil
+}
 
-		defer appClient.DaemonSets(defaultNamespace).Delete(name, nil)
+// collect gathers certifications of control plane and also kubelets if it is required
+func (o *ExpirationOptions) collect() ([]serverCertification, error) {
+	k, err := o.newKrawler()
+	if err != nil {
+		return nil, err
 	}
+	defer k.delete()
+	return o.collectWith(k)
+}
+
+// collectWith gathers certifications by the clients of k, the daemon-set of k is deployed if it is needed
+func (o *ExpirationOptions) collectWith(k *krawler) ([]serverCertification, error) {
+	var err error
+	checkKubeletWithCA = false
+	coreclient := k.coreclient
 
 	apiServerPods, err := getPods(
 		coreclient, kubesystemNamespace, "component=kube-apiserver,tier=control-plane")
 	if err != nil {
-		fmt.Println("Apiserver is not exists. Skip.")
+		fmt.Fprintln(o.ErrOut, "Apiserver is not exists. Skip.")
+	}
+
+	etcdPods, err := getPods(
+		coreclient, kubesystemNamespace, "component=etcd,tier=control-plane")
+	if err != nil {
+		fmt.Fprintln(o.ErrOut, "Etcd is not exists. Skip.")
 	}
 
 	controllerManagerPods, err := getPods(
 		coreclient, kubesystemNamespace, "component=kube-controller-manager,tier=control-plane")
 	if err != nil {
-		fmt.Println("ControllerManager is not exists. Skip.")
+		fmt.Fprintln(o.ErrOut, "ControllerManager is not exists. Skip.")
 	}
 
 	schedulerManagerPods, err := getPods(
 		coreclient, kubesystemNamespace, "component=kube-scheduler,tier=control-plane")
 	if err != nil {
-		fmt.Println("Scheduler is not exists. Skip.")
+		fmt.Fprintln(o.ErrOut, "Scheduler is not exists. Skip.")
 	}
 
-	dsPodCount := 0
+	// readers of files for images without cat run only on these nodes with only the directories of the files
+	k.mount(apiServerPods, etcdPods, controllerManagerPods, schedulerManagerPods)
 	if o.checkKubelet {
-		appClient, err := appsV1Client.NewForConfig(clientConfig)
-		if err != nil {
-			return err
+		if err := k.create(&k.kubelets); err != nil {
+			return nil, err
 		}
+	}
 
-		dsClient := appClient.DaemonSets(defaultNamespace)
+	dsPodCount := 0
+	if o.checkKubelet {
+		dsPodCount, err = k.desiredPods()
 		if err != nil {
-			fmt.Println("Exist already")
-		} else {
-			for {
-				time.Sleep(time.Second / 2)
-
-				ds, err := dsClient.Get(name, meta_v1.GetOptions{})
-				if err != nil {
-					return err
-				}
-				if ds.Status.DesiredNumberScheduled > 0 {
-					dsPodCount = int(ds.Status.DesiredNumberScheduled)
-					break
-				}
-			}
+			return nil, err
 		}
 	}
 
-	bar := pb.New(len(apiServerPods.Items) + len(controllerManagerPods.Items) + len(schedulerManagerPods.Items) + dsPodCount)
+	bar := pb.New(len(apiServerPods.Items) + len(etcdPods.Items) + len(controllerManagerPods.Items) + len(schedulerManagerPods.Items) + dsPodCount)
 	bar.SetWidth(80)
 	bar.SetMaxWidth(80)
+	// keep stdout clean for machine readable outputs
+	bar.Output = o.ErrOut
+	if !o.progress {
+		bar.Output = ioutil.Discard
+	}
 	bar.Start()
 
 	var wg sync.WaitGroup
@@ -304,55 +637,14 @@ func (o *ExpirationOptions) Run(cmd *cobra.Command) error {
 		wg.Add(1)
 		go func(p corev1.Pod) {
 			defer wg.Done()
-			for _, c := range p.Spec.Containers[0].Command {
-				// only for options
-				if strings.HasPrefix(c, "--") {
-					s := strings.Split(c[2:], "=")
-					if s[0] == kubeletCAFlag && s[1] != "" {
-						mutex.Lock()
-						checkKubeletWithCA = true
-						mutex.Unlock()
-					}
-					for _, co := range certOptions {
-						if co == s[0] {
-							cert, err := ExecPod(coreclient, kubesystemNamespace, &p, []string{"cat", s[1]})
-							errorStr := ""
-							if err != nil {
-								errorStr = err.Error()
-							} else {
-								date, days, err := GetDateAndDaysFromCert(cert)
-								if err != nil {
-									errorStr = err.Error()
-								} else {
-									channel <- serverCertification{
-										Entry: Entry{
-											Type: "apiserver",
-											Node: p.Spec.NodeName,
-											Name: s[0],
-											Path: s[1],
-											Days: days,
-											Due:  date,
-										},
-										Warning: "",
-									}
-								}
-							}
-							if errorStr != "" {
-								channel <- serverCertification{
-									Entry: Entry{
-										Type: "apiserver",
-										Node: p.Spec.NodeName,
-										Name: s[0],
-										Path: s[1],
-										Days: 0,
-										Due:  time.Time{},
-									},
-									Warning: err.Error(),
-								}
-							}
-						}
-					}
-				}
+			flags := getFlags(podCommand(&p))
+			if flags[kubeletCAFlag] != "" {
+				mutex.Lock()
+				checkKubeletWithCA = true
+				mutex.Unlock()
+			}
+			for _, c := range apiserverCertifications(k, &p) {
+				channel <- c
 			}
 			mutex.Lock()
 			bar.Increment()
@@ -360,75 +652,25 @@ func (o *ExpirationOptions) Run(cmd *cobra.Command) error {
 		}(apiServerPod)
 	}
 
-	for _, controllerManagerPod := range controllerManagerPods.Items {
+	for _, etcdPod := range etcdPods.Items {
 		wg.Add(1)
 		go func(p corev1.Pod) {
 			defer wg.Done()
-			for _, c := range p.Spec.Containers[0].Command {
-				// only for options
-				if strings.HasPrefix(c, "--") {
-					s := strings.Split(c[2:], "=")
-					if s[0] == kubeConfigFlag {
-						errorResult := func(node string, errstr string) {
-							channel <- serverCertification{
-								Entry: Entry{
-									Type: "controller-manager",
-									Node: node,
-									Name: "client-cert",
-									Path: "-",
-									Days: 0,
-									Due:  time.Time{},
-								},
-								Warning: errstr,
-							}
-						}
-						kubeconfig, err := ExecPod(coreclient, kubesystemNamespace, &p, []string{"cat", s[1]})
-						if err != nil {
-							errorResult(p.Spec.NodeName, err.Error())
-						}
-						cfg, err := clientcmd.NewClientConfigFromBytes([]byte(kubeconfig))
-						if err != nil {
-							errorResult(p.Spec.NodeName, err.Error())
-						}
-						rawConfig, err := cfg.RawConfig()
-						if err != nil {
-							errorResult(p.Spec.NodeName, err.Error())
-						}
-
-						var cert string
-						var path string
-						currentContext := rawConfig.CurrentContext
-						user := rawConfig.Contexts[currentContext].AuthInfo
-						u := rawConfig.AuthInfos[user]
-
-						if string(u.ClientCertificateData) != "" {
-							cert = string(u.ClientCertificateData)
-							path = s[1]
-						} else if string(u.ClientCertificate) != "" {
-							cert, err = ExecPod(coreclient, kubesystemNamespace, &p, []string{"cat", string(u.ClientCertificate)})
-							if err != nil {
-								errorResult(p.Spec.NodeName, err.Error())
-							}
-							path = string(u.ClientCertificate)
-						} else {
-							errorResult(p.Spec.NodeName, err.Error())
-						}
+			for _, c := range etcdCertifications(k, &p) {
+				channel <- c
+			}
+			mutex.Lock()
+			bar.Increment()
+			mutex.Unlock()
+		}(etcdPod)
+	}
 
-						date, days, err := GetDateAndDaysFromCert(cert)
-						channel <- serverCertification{
-							Entry: Entry{
-								Type: "controller-manager",
-								Node: p.Spec.NodeName,
-								Name: "client-cert",
-								Path: path,
-								Days: days,
-								Due:  date,
-							},
-							Warning: "",
-						}
-						break
-					}
-				}
+	for _, controllerManagerPod := range controllerManagerPods.Items {
+		wg.Add(1)
+		go func(p corev1.Pod) {
+			defer wg.Done()
+			for _, c := range componentKubeconfigCertifications(k, &p, "controller-manager") {
+				channel <- c
 			}
 			mutex.Lock()
 			bar.Increment()
@@ -440,71 +682,8 @@ func (o *ExpirationOptions) Run(cmd *cobra.Command) error {
 		wg.Add(1)
 		go func(p corev1.Pod) {
 			defer wg.Done()
-			for _, c := range p.Spec.Containers[0].Command {
-				// only for options
-				if strings.HasPrefix(c, "--") {
-					s := strings.Split(c[2:], "=")
-					if s[0] == kubeConfigFlag {
-						errorResult := func(node string, errstr string) {
-							channel <- serverCertification{
-								Entry: Entry{
-									Type: "scheduler",
-									Node: node,
-									Name: "client-cert",
-									Path: "-",
-									Days: 0,
-									Due:  time.Time{},
-								},
-								Warning: errstr,
-							}
-						}
-						kubeconfig, err := ExecPod(coreclient, kubesystemNamespace, &p, []string{"cat", s[1]})
-						if err != nil {
-							errorResult(p.Spec.NodeName, err.Error())
-						}
-						cfg, err := clientcmd.NewClientConfigFromBytes([]byte(kubeconfig))
-						if err != nil {
-							errorResult(p.Spec.NodeName, err.Error())
-						}
-						rawConfig, err := cfg.RawConfig()
-						if err != nil {
-							errorResult(p.Spec.NodeName, err.Error())
-						}
-
-						var cert string
-						var path string
-						currentContext := rawConfig.CurrentContext
-						user := rawConfig.Contexts[currentContext].AuthInfo
-						u := rawConfig.AuthInfos[user]
-
-						if string(u.ClientCertificateData) != "" {
-							cert = string(u.ClientCertificateData)
-							path = s[1]
-						} else if string(u.ClientCertificate) != "" {
-							cert, err = ExecPod(coreclient, kubesystemNamespace, &p, []string{"cat", string(u.ClientCertificate)})
-							if err != nil {
-								errorResult(p.Spec.NodeName, err.Error())
-							}
-							path = string(u.ClientCertificate)
-						} else {
-							errorResult(p.Spec.NodeName, err.Error())
-						}
-
-						date, days, err := GetDateAndDaysFromCert(cert)
-						channel <- serverCertification{
-							Entry: Entry{
-								Type: "scheduler",
-								Node: p.Spec.NodeName,
-								Name: "client-cert",
-								Path: path,
-								Days: days,
-								Due:  date,
-							},
-							Warning: "",
-						}
-						break
-					}
-				}
+			for _, c := range componentKubeconfigCertifications(k, &p, "scheduler") {
+				channel <- c
 			}
 			mutex.Lock()
 			bar.Increment()
@@ -513,36 +692,16 @@ func (o *ExpirationOptions) Run(cmd *cobra.Command) error {
 	}
 
 	if o.checkKubelet {
-		appClient, err := appsV1Client.NewForConfig(clientConfig)
+		krawlerPods, err := k.runningPods()
 		if err != nil {
-			return err
-		}
-
-		dsClient := appClient.DaemonSets(defaultNamespace)
-		if err != nil {
-			fmt.Println("Exist already")
-		} else {
-			for {
-				time.Sleep(time.Second / 2)
-
-				ds, err := dsClient.Get(name, meta_v1.GetOptions{})
-				if err != nil {
-					return err
-				}
-				if ds.Status.NumberAvailable == ds.Status.DesiredNumberScheduled && ds.Status.DesiredNumberScheduled > 0 {
-					time.Sleep(time.Second)
-					break
-				}
-			}
+			return nil, err
 		}
-
-		krawlerPods, err := getPods(
-			coreclient, defaultNamespace, fmt.Sprintf("app=%s", name))
-		if err != nil {
-			return err
+		for _, c := range k.notRunningCertifications() {
+			serverCertifications = append(serverCertifications, c)
+			bar.Increment()
 		}
 
-		for _, p := range krawlerPods.Items {
+		for _, p := range krawlerPods {
 			wg.Add(1)
 			go func(p corev1.Pod) {
 				defer wg.Done()
@@ -552,13 +711,20 @@ func (o *ExpirationOptions) Run(cmd *cobra.Command) error {
 					}
 				}
 				// p.Spec.NodeName
-				var command string
+				var (
+					command string
+					sent    time.Time
+					err     error
+				)
+				// days of the entries are evaluated again at --at or --in by classify
 				for i := 1; i <= 5; i++ {
+					sent = time.Now()
 					command, err = ExecPod(
 						coreclient,
 						defaultNamespace,
 						&p,
 						[]string{"krawler"},
+						o.ErrOut,
 					)
 					if err == nil {
 						break
@@ -567,28 +733,42 @@ func (o *ExpirationOptions) Run(cmd *cobra.Command) error {
 				}
 
 				if value, ok := isJSON(command); ok {
+					var (
+						skewWarning string
+						clock       time.Time
+					)
+					// older agents do not report the time of the node
+					if value.Now != nil {
+						received := time.Now()
+						skewWarning = clockSkewWarning(*value.Now, sent, received, o.maxClockSkew)
+						clock = value.Now.Add(o.evaluationTime().Sub(received))
+					}
 					for _, v := range value.Entries {
 						warn := ""
 						if v.Name == "server-cert" && checkKubeletWithCA == false {
 							warn = "Can be ignored this."
 						}
-						channel <- serverCertification{
+						c := serverCertification{
 							Entry:   v,
 							Warning: warn,
 						}
+						if v.Warning != "" {
+							c.addWarning(v.Warning)
+							c.Entry.Warning = ""
+						}
+						c.clock = clock
+						if skewWarning != "" {
+							c.addWarning(skewWarning)
+						}
+						// only public certificates are shipped by the agent to verify them here
+						if v.Certificate != "" {
+							c.certs, _ = GetCertificatesFromPEM(v.Certificate)
+							c.Entry.Certificate = ""
+						}
+						channel <- c
 					}
 				} else {
-					channel <- serverCertification{
-						Entry: Entry{
-							Type: "kubelet",
-							Node: p.Spec.NodeName,
-							Name: "Error",
-							Path: "",
-							Days: 0,
-							Due:  time.Now(),
-						},
-						Warning: command,
-					}
+					channel <- kubeletErrorCertification(p.Spec.NodeName, command, err)
 				}
 				mutex.Lock()
 				bar.Increment()
@@ -610,47 +790,56 @@ func (o *ExpirationOptions) Run(cmd *cobra.Command) error {
 		default:
 			err := fmt.Errorf("Unknown type %T, %+v", result, result)
 			log.Error(err)
-			return nil
+			return nil, err
 		}
 	}
 
 	bar.Finish()
-	sort.Slice(serverCertifications, func(i, j int) bool {
-		if serverCertifications[i].Entry.Type == "scheduler" && serverCertifications[j].Entry.Type == "kubelet" {
-			return true
-		}
-		if serverCertifications[i].Entry.Type == "kubelet" && serverCertifications[j].Entry.Type == "scheduler" {
-			return false
-		}
-		if serverCertifications[i].Entry.Type < serverCertifications[j].Entry.Type {
-			return true
-		}
-		if serverCertifications[i].Entry.Type > serverCertifications[j].Entry.Type {
-			return false
-		}
-		if serverCertifications[i].Entry.Node < serverCertifications[j].Entry.Node {
-			return true
-		}
-		if serverCertifications[i].Entry.Node > serverCertifications[j].Entry.Node {
-			return false
+
+	if o.includeSecrets {
+		serverCertifications = append(serverCertifications, collectSecrets(coreclient, o.secretNamespaces)...)
+	}
+
+	if o.includeCABundles {
+		dynamicClient, err := dynamic.NewForConfig(clientConfig)
+		if err != nil {
+			return nil, err
 		}
-		return serverCertifications[i].Entry.Name < serverCertifications[j].Entry.Name
-	})
+		serverCertifications = append(serverCertifications, collectCABundles(dynamicClient)...)
+	}
 
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Type", "Node", "Name", "Days", "Due", "Path", "Warning"})
+	o.audit(serverCertifications, o.apiserverCA())
 
-	for _, v := range serverCertifications {
-		m := []string{v.Entry.Type, v.Entry.Node, v.Entry.Name, cast.ToString(v.Entry.Days), v.Entry.Due.String(), v.Entry.Path, v.Warning}
-		table.Append(m)
+	if targets, err := collectSANTargets(coreclient, clientConfig.Host); err != nil {
+		fmt.Fprintf(o.ErrOut, "Failed to find addresses of nodes: %s. Skip SAN audit.\n", err)
+	} else {
+		auditSANs(serverCertifications, targets)
 	}
-	table.Render() // Send output
 
-	return nil
+	sortCertifications(serverCertifications, defaultSort)
+
+	return serverCertifications, nil
+}
+
+// kubeletErrorCertification is the error of kubelets on node whose agent failed by err or printed command which is not json
+func kubeletErrorCertification(node string, command string, err error) serverCertification {
+	warning := command
+	if err != nil {
+		warning = err.Error()
+	}
+	return serverCertification{
+		Entry: Entry{
+			Type: "kubelet",
+			Node: node,
+			Name: "Error",
+		},
+		Warning: warning,
+	}
 }
 
-// ExecPod sets all information required for updating the current context
-func ExecPod(coreclient *coreV1Client.CoreV1Client, namespace string, pod *corev1.Pod, command []string) (string, error) {
+// ExecPod sets all information required for updating the current context.
+// Stderr of the command is written to errOut to keep stdout of the plugin clean for machine readable outputs.
+func ExecPod(coreclient *coreV1Client.CoreV1Client, namespace string, pod *corev1.Pod, command []string, errOut io.Writer) (string, error) {
 	req := coreclient.RESTClient().
 		Post().
 		Namespace(namespace).
@@ -671,8 +860,13 @@ func ExecPod(coreclient *coreV1Client.CoreV1Client, namespace string, pod *corev
 		return "", fmt.Errorf("%s: %s", req.URL().String(), err.Error())
 	}
 
+	return streamExec(exec, command, errOut)
+}
+
+// streamExec runs the command by exec and returns its stdout, its stderr is written to errOut
+func streamExec(exec remotecommand.Executor, command []string, errOut io.Writer) (string, error) {
 	var stdout, stderr bytes.Buffer
-	err = exec.Stream(remotecommand.StreamOptions{
+	err := exec.Stream(remotecommand.StreamOptions{
 		Stdin:  nil,
 		Stdout: &stdout,
 		Stderr: &stderr,
@@ -680,29 +874,27 @@ func ExecPod(coreclient *coreV1Client.CoreV1Client, namespace string, pod *corev
 	})
 
 	if err != nil {
+		// stderr explains the failure, e.g. the command is not found in the image
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s: %s", strings.Join(command, " "), err.Error(), msg)
+		}
 		return "", fmt.Errorf("%s: %s", strings.Join(command, " "), err.Error())
 	}
 
 	if stderr.String() != "" {
-		fmt.Println("Error : " + stderr.String())
+		fmt.Fprintln(errOut, "Error : "+stderr.String())
 	}
 
 	return stdout.String(), nil
 }
 
-// GetDateAndDaysFromCert takes a cert and extract Date and Days
+// GetDateAndDaysFromCert takes a cert and extract Date and Days of the earliest expiring certificate in it
 func GetDateAndDaysFromCert(cert string) (time.Time, int, error) {
-	var err error
-	timeNow := time.Now()
-	block, _ := pem.Decode([]byte(cert))
-	if block == nil {
-		return time.Time{}, 0, fmt.Errorf("failed to parse certificate PEM")
-	}
-	c, err := x509.ParseCertificate(block.Bytes)
+	certs, err := GetCertificatesFromPEM(cert)
 	if err != nil {
-		return time.Time{}, 0, fmt.Errorf("failed to parse certificate: " + err.Error())
+		return time.Time{}, 0, err
 	}
-	expiresIn := int(c.NotAfter.Sub(timeNow).Hours() / 24)
+	notAfter := earliestCertificate(certs).NotAfter
 
-	return c.NotAfter, expiresIn, err
+	return notAfter, daysUntil(notAfter), nil
 }
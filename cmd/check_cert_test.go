@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+func TestGetFlags(t *testing.T) {
+	flags := getFlags([]string{
+		"kube-apiserver",
+		"--authorization-mode=Node,RBAC",
+		"--allow-privileged",
+		"--tls-cert-file=/etc/kubernetes/pki/apiserver.crt",
+		"--admission-control-config-file=/etc/a=b.yaml",
+	})
+
+	assert.Equal(t, "Node,RBAC", flags["authorization-mode"])
+	assert.Equal(t, "/etc/kubernetes/pki/apiserver.crt", flags["tls-cert-file"])
+	assert.Equal(t, "/etc/a=b.yaml", flags["admission-control-config-file"])
+	v, ok := flags["allow-privileged"]
+	assert.True(t, ok)
+	assert.Equal(t, "", v)
+	assert.Equal(t, 4, len(flags))
+}
+
+func TestHealthcheckCertPath(t *testing.T) {
+	p := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Command: []string{"etcd", "--cert-file=/etc/kubernetes/pki/etcd/server.crt"},
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						Exec: &corev1.ExecAction{
+							Command: []string{
+								"/bin/sh",
+								"-ec",
+								"ETCDCTL_API=3 etcdctl --endpoints=https://[127.0.0.1]:2379 --cacert=/etc/kubernetes/pki/etcd/ca.crt --cert=/etc/kubernetes/pki/etcd/healthcheck-client.crt --key=/etc/kubernetes/pki/etcd/healthcheck-client.key get foo",
+							},
+						},
+					},
+				},
+			}},
+		},
+	}
+
+	assert.Equal(t, "/etc/kubernetes/pki/etcd/healthcheck-client.crt", healthcheckCertPath(p))
+	assert.Equal(t, "/etc/kubernetes/pki/etcd/healthcheck-client.key", healthcheckKeyPath(p))
+
+	p.Spec.Containers[0].LivenessProbe = nil
+	assert.Equal(t, "", healthcheckCertPath(p))
+	assert.Equal(t, "", healthcheckKeyPath(p))
+
+	// kubeadm 1.16 and later probe etcd by http
+	p.Spec.Containers[0].Command = append(p.Spec.Containers[0].Command, "--trusted-ca-file=/etc/kubernetes/pki/etcd/ca.crt")
+	p.Spec.Containers[0].LivenessProbe = &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{Path: "/health", Host: "127.0.0.1"},
+		},
+	}
+	assert.Equal(t, "/etc/kubernetes/pki/etcd/healthcheck-client.crt", healthcheckCertPath(p))
+	assert.Equal(t, "/etc/kubernetes/pki/etcd/healthcheck-client.key", healthcheckKeyPath(p))
+}
+
+func TestEtcdCertificationsWithHTTPProbe(t *testing.T) {
+	due := time.Now().Add(100 * 24 * time.Hour).UTC().Truncate(time.Second)
+	ca := newTestCA(t, "etcd-ca", due.Add(3650*24*time.Hour), nil)
+	client := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "kube-etcd-healthcheck-client"}, NotBefore: time.Now(), NotAfter: due}, ca)
+	p := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName: "master1",
+			Containers: []corev1.Container{{
+				Command: []string{"etcd", "--trusted-ca-file=/etc/kubernetes/pki/etcd/ca.crt"},
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Path: "/health"}},
+				},
+			}},
+		},
+	}
+
+	certs := etcdCertifications(fakeReader{
+		"/etc/kubernetes/pki/etcd/ca.crt":                 ca.pem,
+		"/etc/kubernetes/pki/etcd/healthcheck-client.crt": client.pem,
+		"/etc/kubernetes/pki/etcd/healthcheck-client.key": client.keyPem,
+	}, p)
+	if assert.Equal(t, 2, len(certs)) {
+		assert.Equal(t, healthcheckClientName, certs[1].Entry.Name)
+		assert.Equal(t, "/etc/kubernetes/pki/etcd/healthcheck-client.crt", certs[1].Entry.Path)
+		assert.Equal(t, due, certs[1].Entry.Due)
+		assert.Equal(t, "", certs[1].Warning)
+	}
+
+	// clusters not by kubeadm have no healthcheck-client
+	certs = etcdCertifications(fakeReader{"/etc/kubernetes/pki/etcd/ca.crt": ca.pem}, p)
+	assert.Equal(t, 1, len(certs))
+}
+
+func TestEvaluationTime(t *testing.T) {
+	o := NewExpirationOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.at = "2020-06-01T00:00:00Z"
+	assert.NoError(t, o.Validate())
+	assert.Equal(t, time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC), o.evaluationTime())
+
+	o.in = time.Hour
+	assert.Error(t, o.Validate())
+
+	o = NewExpirationOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.in = 90 * 24 * time.Hour
+	assert.NoError(t, o.Validate())
+	assert.WithinDuration(t, time.Now().Add(90*24*time.Hour), o.evaluationTime(), time.Minute)
+
+	o = NewExpirationOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.at = "2020-06-01"
+	assert.Error(t, o.Validate())
+}
+
+// fakeExecutor writes stdout and stderr like a command run in a pod
+type fakeExecutor struct {
+	stdout string
+	stderr string
+	err    error
+}
+
+func (e fakeExecutor) Stream(options remotecommand.StreamOptions) error {
+	options.Stdout.Write([]byte(e.stdout))
+	options.Stderr.Write([]byte(e.stderr))
+	return e.err
+}
+
+func TestStreamExecFailureHasStderr(t *testing.T) {
+	errOut := &bytes.Buffer{}
+	_, err := streamExec(fakeExecutor{
+		stderr: `exec: "cat": executable file not found in $PATH` + "\n",
+		err:    fmt.Errorf("command terminated with exit code 126"),
+	}, []string{"cat", "/etc/kubernetes/pki/ca.crt"}, errOut)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), missingExecutable)
+		assert.Contains(t, err.Error(), "cat /etc/kubernetes/pki/ca.crt")
+	}
+	assert.Equal(t, "", errOut.String())
+}
+
+func TestStreamExecKeepsStdoutClean(t *testing.T) {
+	streams, _, out, errOut := genericclioptions.NewTestIOStreams()
+	o := NewExpirationOptions(streams)
+	*o.printFlags.OutputFormat = "json"
+	assert.NoError(t, o.validateOutput())
+
+	agentOut, err := streamExec(fakeExecutor{
+		stdout: `{"entry":[{"type":"kubelet","node":"node1","name":"client-cert","days":10,"due":"2019-01-01T00:00:00Z","path":"/var/lib/kubelet/pki/kubelet-client-current.pem"}]}`,
+		stderr: "cannot read /var/lib/kubelet/pki/kubelet.crt",
+	}, []string{"krawler"}, o.ErrOut)
+	assert.NoError(t, err)
+
+	value, ok := isJSON(agentOut)
+	assert.True(t, ok)
+	assert.NoError(t, o.printCertifications([]serverCertification{{Entry: value.Entries[0], Status: statusOK}}))
+
+	list := CertificationList{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &list))
+	assert.Equal(t, 1, len(list.Items))
+	assert.Contains(t, errOut.String(), "cannot read /var/lib/kubelet/pki/kubelet.crt")
+}
+
+func TestKubeletErrorCertification(t *testing.T) {
+	c := kubeletErrorCertification("node-1", "", fmt.Errorf("krawler: command terminated with exit code 1: open /var/lib/kubelet/config.yaml: permission denied"))
+	assert.Equal(t, Entry{Type: "kubelet", Node: "node-1", Name: "Error"}, c.Entry)
+	assert.Equal(t, "krawler: command terminated with exit code 1: open /var/lib/kubelet/config.yaml: permission denied", c.Warning)
+
+	// an agent which printed something else than json
+	c = kubeletErrorCertification("node-1", "unknown flag", nil)
+	assert.Equal(t, "unknown flag", c.Warning)
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// column is a single header and the jsonpath printed under it
+type column struct {
+	header   string
+	template *jsonpath.JSONPath
+}
+
+// customColumnsPrinter prints every certification as a row like `kubectl get -o custom-columns`
+type customColumnsPrinter struct {
+	columns []column
+}
+
+func newCustomColumnsPrinter(format string, arg string) (*customColumnsPrinter, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("%s format specified but no custom columns given", format)
+	}
+
+	var headers, paths []string
+	if format == customColumnsFileOutput {
+		data, err := ioutil.ReadFile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("error reading template %s, %v", arg, err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 {
+			return nil, fmt.Errorf("unexpected custom columns file %s: expected a header line and a path line", arg)
+		}
+		headers = strings.Fields(lines[0])
+		paths = strings.Fields(lines[1])
+		if len(headers) != len(paths) {
+			return nil, fmt.Errorf("number of headers (%d) and paths (%d) don't match in %s", len(headers), len(paths), arg)
+		}
+	} else {
+		for _, spec := range strings.Split(arg, ",") {
+			s := strings.SplitN(spec, ":", 2)
+			if len(s) != 2 {
+				return nil, fmt.Errorf("unexpected custom-columns spec: %s, expected <header>:<json-path-expr>", spec)
+			}
+			headers = append(headers, s[0])
+			paths = append(paths, s[1])
+		}
+	}
+
+	p := &customColumnsPrinter{}
+	for i := range headers {
+		template := jsonpath.New(headers[i]).AllowMissingKeys(true)
+		if err := template.Parse(relaxedJSONPath(paths[i])); err != nil {
+			return nil, fmt.Errorf("unexpected path string %q: %v", paths[i], err)
+		}
+		p.columns = append(p.columns, column{header: headers[i], template: template})
+	}
+
+	return p, nil
+}
+
+// relaxedJSONPath accepts `.entry.node`, `entry.node` and `{.entry.node}` alike
+func relaxedJSONPath(path string) string {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	if !strings.HasPrefix(path, ".") {
+		path = "." + path
+	}
+	return "{" + path + "}"
+}
+
+func (p *customColumnsPrinter) print(out io.Writer, certs []serverCertification) error {
+	items := make([]interface{}, len(certs))
+	for i := range certs {
+		items[i] = certs[i]
+	}
+	return p.printItems(out, items)
+}
+
+// printItems prints a row of every item by its json representation
+func (p *customColumnsPrinter) printItems(out io.Writer, items []interface{}) error {
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+
+	headers := make([]string, len(p.columns))
+	for i, c := range p.columns {
+		headers[i] = c.header
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+
+	for _, v := range items {
+		// jsonpath works on the same representation that -o json prints
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var item interface{}
+		if err := json.Unmarshal(data, &item); err != nil {
+			return err
+		}
+
+		fields := make([]string, len(p.columns))
+		for i, c := range p.columns {
+			results, err := c.template.FindResults(item)
+			if err != nil {
+				return err
+			}
+			values := []string{}
+			for _, r := range results {
+				for _, value := range r {
+					values = append(values, fmt.Sprintf("%v", reflect.Indirect(value).Interface()))
+				}
+			}
+			if len(values) == 0 {
+				values = append(values, "<none>")
+			}
+			fields[i] = strings.Join(values, ",")
+		}
+		fmt.Fprintln(w, strings.Join(fields, "\t"))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// orders of --sort-by
+const (
+	defaultSort = ""
+	sortByDays  = "days"
+	sortByDue   = "due"
+	sortByNode  = "node"
+	sortByType  = "type"
+)
+
+var sortOrders = []string{sortByDays, sortByDue, sortByNode, sortByType}
+
+// certificationFilter selects certifications by glob patterns of their type, node and name and by their due
+type certificationFilter struct {
+	types []string
+	nodes []string
+	names []string
+	// expiringWithin keeps only certifications due within this from the evaluation time if it is positive
+	expiringWithin time.Duration
+}
+
+func (f *certificationFilter) validate() error {
+	for _, p := range append(append(append([]string{}, f.types...), f.nodes...), f.names...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %s", p, err)
+		}
+	}
+	if f.expiringWithin < 0 {
+		return fmt.Errorf("--expiring-within must not be negative")
+	}
+	return nil
+}
+
+// filter returns certifications matching every selector at now.
+// Certifications which could not be collected are kept by --expiring-within not to hide failures.
+func (f *certificationFilter) filter(certs []serverCertification, now time.Time) []serverCertification {
+	result := []serverCertification{}
+	for _, v := range certs {
+		if !matchAny(f.types, v.Entry.Type) || !matchAny(f.nodes, v.Entry.Node) || !matchAny(f.names, v.Entry.Name) {
+			continue
+		}
+		if f.expiringWithin > 0 && !v.Entry.Due.IsZero() && v.Entry.Due.After(now.Add(f.expiringWithin)) {
+			continue
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+// matchAny tells whether s matches any of patterns, or true without patterns
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func validateSortBy(by string) error {
+	if by == defaultSort {
+		return nil
+	}
+	for _, o := range sortOrders {
+		if by == o {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown --sort-by %q, allowed orders are: %s", by, strings.Join(sortOrders, ","))
+}
+
+// sortCertifications sorts certifications by type, node, name, path and position in their bundle,
+// and then by the given order keeping the rest of the order
+func sortCertifications(certs []serverCertification, by string) {
+	sort.Slice(certs, func(i, j int) bool {
+		return lessByDefault(certs[i].Entry, certs[j].Entry)
+	})
+
+	// certifications which could not be collected come first by days and due
+	switch by {
+	case sortByDays:
+		sort.SliceStable(certs, func(i, j int) bool {
+			if certs[i].Entry.Due.IsZero() || certs[j].Entry.Due.IsZero() {
+				return certs[i].Entry.Due.IsZero() && !certs[j].Entry.Due.IsZero()
+			}
+			return certs[i].Entry.Days < certs[j].Entry.Days
+		})
+	case sortByDue:
+		sort.SliceStable(certs, func(i, j int) bool {
+			return certs[i].Entry.Due.Before(certs[j].Entry.Due)
+		})
+	case sortByNode:
+		sort.SliceStable(certs, func(i, j int) bool {
+			return certs[i].Entry.Node < certs[j].Entry.Node
+		})
+	case sortByType:
+		sort.SliceStable(certs, func(i, j int) bool {
+			a, b := certs[i].Entry, certs[j].Entry
+			if a.Type != b.Type {
+				return lessByType(a.Type, b.Type)
+			}
+			if a.Node != b.Node {
+				return a.Node < b.Node
+			}
+			return a.Name < b.Name
+		})
+	}
+}
+
+// typeRank orders types of certifications, the control plane first and then kubelets.
+// Other types come after them by name.
+var typeRank = map[string]int{
+	"apiserver":          1,
+	"controller-manager": 2,
+	"etcd":               3,
+	"scheduler":          4,
+	"kubelet":            5,
+}
+
+func lessByType(a string, b string) bool {
+	rankA, rankedA := typeRank[a]
+	rankB, rankedB := typeRank[b]
+	if rankedA && rankedB {
+		return rankA < rankB
+	}
+	if rankedA != rankedB {
+		return rankedA
+	}
+	return a < b
+}
+
+func lessByDefault(a Entry, b Entry) bool {
+	if a.Type != b.Type {
+		return lessByType(a.Type, b.Type)
+	}
+	if a.Node != b.Node {
+		return a.Node < b.Node
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	if a.Path != b.Path {
+		return a.Path < b.Path
+	}
+	return a.Position < b.Position
+}
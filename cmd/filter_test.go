@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func filterTestCertifications(now time.Time) []serverCertification {
+	return []serverCertification{
+		{Entry: Entry{Type: "apiserver", Node: "master1", Name: "tls-cert-file", Days: 300, Due: now.Add(300 * 24 * time.Hour)}},
+		{Entry: Entry{Type: "kubelet", Node: "node-1", Name: "server-cert", Days: 10, Due: now.Add(10 * 24 * time.Hour)}},
+		{Entry: Entry{Type: "kubelet", Node: "node-2", Name: "client-cert", Days: 20, Due: now.Add(20 * 24 * time.Hour)}},
+		{Entry: Entry{Type: "kubelet", Node: "node-3", Name: "Error"}},
+		{Entry: Entry{Type: "scheduler", Node: "master1", Name: "client-cert", Days: 5, Due: now.Add(5 * 24 * time.Hour)}},
+	}
+}
+
+func entryNodes(certs []serverCertification) []string {
+	nodes := []string{}
+	for _, v := range certs {
+		nodes = append(nodes, v.Entry.Type+"/"+v.Entry.Node)
+	}
+	return nodes
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Now()
+	certs := filterTestCertifications(now)
+
+	f := certificationFilter{types: []string{"kubelet"}, nodes: []string{"node-[12]"}}
+	assert.Equal(t, []string{"kubelet/node-1", "kubelet/node-2"}, entryNodes(f.filter(certs, now)))
+
+	f = certificationFilter{names: []string{"client-cert"}}
+	assert.Equal(t, []string{"kubelet/node-2", "scheduler/master1"}, entryNodes(f.filter(certs, now)))
+
+	// failures are kept not to be hidden
+	f = certificationFilter{expiringWithin: 15 * 24 * time.Hour}
+	assert.Equal(t, []string{"kubelet/node-1", "kubelet/node-3", "scheduler/master1"}, entryNodes(f.filter(certs, now)))
+
+	assert.Equal(t, 5, len((&certificationFilter{}).filter(certs, now)))
+
+	assert.Error(t, (&certificationFilter{nodes: []string{"["}}).validate())
+	assert.Error(t, (&certificationFilter{expiringWithin: -time.Hour}).validate())
+}
+
+func TestSortCertifications(t *testing.T) {
+	now := time.Now()
+	certs := filterTestCertifications(now)
+
+	sortCertifications(certs, defaultSort)
+	assert.Equal(t, []string{"apiserver/master1", "scheduler/master1", "kubelet/node-1", "kubelet/node-2", "kubelet/node-3"}, entryNodes(certs))
+
+	sortCertifications(certs, sortByDays)
+	assert.Equal(t, []string{"kubelet/node-3", "scheduler/master1", "kubelet/node-1", "kubelet/node-2", "apiserver/master1"}, entryNodes(certs))
+
+	sortCertifications(certs, sortByDue)
+	assert.Equal(t, []string{"kubelet/node-3", "scheduler/master1", "kubelet/node-1", "kubelet/node-2", "apiserver/master1"}, entryNodes(certs))
+
+	sortCertifications(certs, sortByNode)
+	assert.Equal(t, []string{"apiserver/master1", "scheduler/master1", "kubelet/node-1", "kubelet/node-2", "kubelet/node-3"}, entryNodes(certs))
+
+	sortCertifications(certs, sortByDays)
+	sortCertifications(certs, sortByType)
+	assert.Equal(t, []string{"apiserver/master1", "scheduler/master1", "kubelet/node-1", "kubelet/node-2", "kubelet/node-3"}, entryNodes(certs))
+
+	assert.NoError(t, validateSortBy(sortByType))
+	assert.Error(t, validateSortBy("name"))
+}
+
+func TestSortCertificationsByTypeRank(t *testing.T) {
+	certs := []serverCertification{
+		{Entry: Entry{Type: "secret", Node: ""}},
+		{Entry: Entry{Type: "kubelet", Node: "node-1"}},
+		{Entry: Entry{Type: "etcd", Node: "master1"}},
+		{Entry: Entry{Type: "apiservice", Node: ""}},
+		{Entry: Entry{Type: "scheduler", Node: "master1"}},
+		{Entry: Entry{Type: "kubelet", Node: "master1"}},
+		{Entry: Entry{Type: "controller-manager", Node: "master1"}},
+		{Entry: Entry{Type: "apiserver", Node: "master1"}},
+		{Entry: Entry{Type: "crd", Node: ""}},
+	}
+
+	sortCertifications(certs, defaultSort)
+	assert.Equal(t, []string{
+		"apiserver/master1",
+		"controller-manager/master1",
+		"etcd/master1",
+		"scheduler/master1",
+		"kubelet/master1",
+		"kubelet/node-1",
+		"apiservice/",
+		"crd/",
+		"secret/",
+	}, entryNodes(certs))
+
+	assert.True(t, lessByType("etcd", "kubelet"))
+	assert.False(t, lessByType("kubelet", "etcd"))
+	assert.True(t, lessByType("kubelet", "apiservice"))
+	assert.True(t, lessByType("apiservice", "secret"))
+}
+
+func TestSortCertificationsByTypeKeepsBundles(t *testing.T) {
+	certs := []serverCertification{
+		{Entry: Entry{Type: "kubelet", Node: "node-1", Name: "server-cert"}},
+		{Entry: Entry{Type: "apiserver", Node: "master1", Name: "client-ca-file", Position: 2}},
+		{Entry: Entry{Type: "apiserver", Node: "master1", Name: "tls-cert-file"}},
+		{Entry: Entry{Type: "apiserver", Node: "master1", Name: "client-ca-file", Position: 0}},
+		{Entry: Entry{Type: "apiserver", Node: "master1", Name: "client-ca-file", Position: 1}},
+	}
+
+	sortCertifications(certs, sortByType)
+	names := []string{}
+	for _, c := range certs {
+		names = append(names, fmt.Sprintf("%s/%s/%d", c.Entry.Type, c.Entry.Name, c.Entry.Position))
+	}
+	assert.Equal(t, []string{
+		"apiserver/client-ca-file/0",
+		"apiserver/client-ca-file/1",
+		"apiserver/client-ca-file/2",
+		"apiserver/tls-cert-file/0",
+		"kubelet/server-cert/0",
+	}, names)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// keyOptions maps flags of certifications to the flag of their private key
+var keyOptions = map[string]string{
+	"tls-cert-file":              "tls-private-key-file",
+	"kubelet-client-certificate": "kubelet-client-key",
+	"proxy-client-cert-file":     "proxy-client-key-file",
+	"etcd-certfile":              "etcd-keyfile",
+	"cert-file":                  "key-file",
+	"peer-cert-file":             "peer-key-file",
+}
+
+// keyPairWarning returns a warning when key is not the private key of the certification.
+// The key itself is never a part of the warning.
+func keyPairWarning(cert string, key string, keyPath string) string {
+	if _, err := tls.X509KeyPair([]byte(cert), []byte(key)); err != nil {
+		return fmt.Sprintf("private key %s does not match the certification: %s", keyPath, err.Error())
+	}
+	return ""
+}
+
+// checkKeyPair adds a warning to the certification read from cert when key does not match it.
+// Nothing is checked for certifications which could not be read.
+func checkKeyPair(certs []serverCertification, cert string, key string, keyPath string, err error) {
+	if len(certs) == 0 || certs[0].Entry.Due.IsZero() {
+		return
+	}
+	if err != nil {
+		certs[0].addWarning(fmt.Sprintf("failed to read private key %s: %s", keyPath, err.Error()))
+		return
+	}
+	if w := keyPairWarning(cert, key, keyPath); w != "" {
+		certs[0].addWarning(w)
+	}
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckKeyPair(t *testing.T) {
+	ca := newTestCA(t, "ca", time.Now().Add(24*time.Hour), nil)
+	other := newTestCA(t, "other", time.Now().Add(24*time.Hour), nil)
+
+	certs := newCertifications("apiserver", "node1", "tls-cert-file", "apiserver.crt", ca.pem, nil)
+	checkKeyPair(certs, ca.pem, ca.keyPem, "apiserver.key", nil)
+	assert.Equal(t, "", certs[0].Warning)
+
+	checkKeyPair(certs, ca.pem, other.keyPem, "apiserver.key", nil)
+	assert.Contains(t, certs[0].Warning, "private key apiserver.key does not match the certification")
+	assert.NotContains(t, certs[0].Warning, "PRIVATE KEY")
+
+	certs = newCertifications("apiserver", "node1", "tls-cert-file", "apiserver.crt", ca.pem, nil)
+	checkKeyPair(certs, ca.pem, "", "apiserver.key", fmt.Errorf("no such file"))
+	assert.Equal(t, "failed to read private key apiserver.key: no such file", certs[0].Warning)
+
+	// certifications which could not be read have nothing to compare
+	certs = newCertifications("apiserver", "node1", "tls-cert-file", "apiserver.crt", "", fmt.Errorf("no such file"))
+	checkKeyPair(certs, "", ca.keyPem, "apiserver.key", nil)
+	assert.Equal(t, "no such file", certs[0].Warning)
+}
@@ -0,0 +1,520 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsV1Client "k8s.io/client-go/kubernetes/typed/apps/v1"
+	coreV1Client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// readerName is the daemon-set of the agent reading files of control plane for images without cat
+	readerName = name + "-reader"
+
+	// directories of the host with files of control plane are mounted under here to be read by the agent
+	hostRootPath = "/host"
+	hostDirName  = "host-dir-"
+	// hostDirsEnv tells the agent the directories of the host it may read, separated by colons
+	hostDirsEnv = "KRAWLER_READ_DIRS"
+
+	krawlerReadFlag = "--read"
+
+	// the error of exec when the image has no cat, e.g. distroless
+	missingExecutable = "executable file not found"
+
+	// krawlerTimeout is how long pods of the daemon-set are waited for to run
+	krawlerTimeout = 2 * time.Minute
+)
+
+// agentSet is a daemon-set of the agent which is deployed when it is needed for the first time
+type agentSet struct {
+	name string
+	// daemonSet makes the daemon-set to deploy
+	daemonSet func() (*appv1.DaemonSet, error)
+
+	createOnce sync.Once
+	created    bool
+	createErr  error
+
+	podsOnce sync.Once
+	pods     []corev1.Pod
+	podsErr  error
+	// notRunning are pods which did not run until the timeout
+	notRunning []corev1.Pod
+}
+
+// krawler deploys the agent to every node to read kubelet certifications,
+// and to nodes of control plane to read their files only if their images have no cat
+type krawler struct {
+	coreclient *coreV1Client.CoreV1Client
+	appClient  *appsV1Client.AppsV1Client
+	// errOut receives stderr of commands run in pods
+	errOut io.Writer
+	// hostDirs are directories of the host mounted to the readers
+	hostDirs []string
+	// nodes are nodes of control plane the readers run on
+	nodes []string
+	// timeout is how long pods of the daemon-sets are waited for
+	timeout time.Duration
+
+	kubelets agentSet
+	readers  agentSet
+}
+
+func newKrawler(coreclient *coreV1Client.CoreV1Client, appClient *appsV1Client.AppsV1Client, errOut io.Writer) *krawler {
+	k := &krawler{
+		coreclient: coreclient,
+		appClient:  appClient,
+		errOut:     errOut,
+		timeout:    krawlerTimeout,
+	}
+	k.kubelets = agentSet{
+		name: name,
+		daemonSet: func() (*appv1.DaemonSet, error) {
+			return newKrawlerDaemonSet(), nil
+		},
+	}
+	k.readers = agentSet{
+		name: readerName,
+		daemonSet: func() (*appv1.DaemonSet, error) {
+			if len(k.nodes) == 0 {
+				return nil, fmt.Errorf("no node of control plane is found to read files on")
+			}
+			return newReaderDaemonSet(k.nodes, topDirs(k.hostDirs)), nil
+		},
+	}
+	return k
+}
+
+// mount adds directories of files the control plane pods are configured with to be mounted to the readers,
+// which run on the nodes of the pods. It takes effect only before the readers are deployed.
+func (k *krawler) mount(pods ...*corev1.PodList) {
+	dirs := k.hostDirs
+	nodes := map[string]bool{}
+	for _, n := range k.nodes {
+		nodes[n] = true
+	}
+	for _, list := range pods {
+		dirs = append(dirs, podHostDirs(list.Items)...)
+		for _, p := range list.Items {
+			if p.Spec.NodeName != "" {
+				nodes[p.Spec.NodeName] = true
+			}
+		}
+	}
+	k.hostDirs = topDirs(dirs)
+	k.nodes = []string{}
+	for n := range nodes {
+		k.nodes = append(k.nodes, n)
+	}
+	sort.Strings(k.nodes)
+}
+
+// podHostDirs finds directories on the host of certifications, private keys and kubeconfigs
+// given to pods by flags through their hostPath volumes
+func podHostDirs(pods []corev1.Pod) []string {
+	dirs := []string{}
+	for i := range pods {
+		p := &pods[i]
+		flags := getFlags(podCommand(p))
+		files := []string{flags[kubeConfigFlag], healthcheckCertPath(p), healthcheckKeyPath(p)}
+		for _, options := range [][]string{certOptions, caOptions, etcdCertOptions, etcdCAOptions} {
+			for _, o := range options {
+				files = append(files, flags[o], flags[keyOptions[o]])
+			}
+		}
+		for _, f := range files {
+			if hostPath, ok := hostPathOf(p, f); ok {
+				dirs = append(dirs, path.Dir(hostPath))
+			}
+		}
+	}
+	return dirs
+}
+
+// hostPathOf finds the path on the host of a file in the main container of p by the hostPath volume mounting it
+func hostPathOf(p *corev1.Pod, file string) (string, bool) {
+	if !path.IsAbs(file) {
+		return "", false
+	}
+	file = path.Clean(file)
+
+	var mount *corev1.VolumeMount
+	for i, m := range p.Spec.Containers[0].VolumeMounts {
+		mountPath := path.Clean(m.MountPath)
+		if file != mountPath && !strings.HasPrefix(file, strings.TrimSuffix(mountPath, "/")+"/") {
+			continue
+		}
+		if mount == nil || len(mountPath) > len(path.Clean(mount.MountPath)) {
+			mount = &p.Spec.Containers[0].VolumeMounts[i]
+		}
+	}
+	if mount == nil {
+		return "", false
+	}
+	for _, v := range p.Spec.Volumes {
+		if v.Name == mount.Name && v.HostPath != nil {
+			return path.Join(v.HostPath.Path, strings.TrimPrefix(file, path.Clean(mount.MountPath))), true
+		}
+	}
+	return "", false
+}
+
+// topDirs cleans dirs with /etc/kubernetes and keeps only ones which are not under the others.
+// The root of the host is never mounted.
+func topDirs(dirs []string) []string {
+	cleaned := []string{path.Clean(etcKubernetesPath)}
+	for _, d := range dirs {
+		if d = path.Clean(d); path.IsAbs(d) && d != "/" {
+			cleaned = append(cleaned, d)
+		}
+	}
+	sort.Strings(cleaned)
+
+	top := []string{}
+	for _, d := range cleaned {
+		if len(top) > 0 && (d == top[len(top)-1] || strings.HasPrefix(d, top[len(top)-1]+"/")) {
+			continue
+		}
+		top = append(top, d)
+	}
+	return top
+}
+
+func newKrawlerDaemonSet() *appv1.DaemonSet {
+	return &appv1.DaemonSet{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: name,
+		},
+		Spec: appv1.DaemonSetSpec{
+			UpdateStrategy: appv1.DaemonSetUpdateStrategy{
+				Type: appv1.RollingUpdateDaemonSetStrategyType,
+				RollingUpdate: &appv1.RollingUpdateDaemonSet{
+					MaxUnavailable: &max,
+				},
+			},
+			Selector: &meta_v1.LabelSelector{
+				MatchLabels: matchLabel,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Labels: matchLabel,
+				},
+				Spec: corev1.PodSpec{
+					HostPID:     true,
+					HostNetwork: true,
+					Containers: []corev1.Container{{
+						Name: name,
+						Env: []corev1.EnvVar{
+							{
+								Name: "NODENAME",
+								ValueFrom: &corev1.EnvVarSource{
+									FieldRef: &corev1.ObjectFieldSelector{
+										FieldPath: "spec.nodeName",
+									},
+								},
+							},
+						},
+						Image:           imageName,
+						ImagePullPolicy: corev1.PullAlways,
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      etcKubernetesName,
+								MountPath: etcKubernetesPath,
+							}, {
+								Name:      varLibKubeletName,
+								MountPath: varLibKubeletPath,
+							}, {
+								Name:      tmpProcName,
+								MountPath: tmpProcPath,
+							},
+						},
+					}},
+					RestartPolicy: corev1.RestartPolicyAlways,
+					Tolerations: []corev1.Toleration{{
+						Operator: corev1.TolerationOpExists,
+					}},
+					Volumes: []corev1.Volume{
+						{
+							Name: etcKubernetesName,
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Type: &hostPathType,
+									Path: etcKubernetesPath,
+								},
+							},
+						}, {
+							Name: varLibKubeletName,
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Type: &hostPathType,
+									Path: varLibKubeletPath,
+								},
+							},
+						}, {
+							Name: tmpProcName,
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Type: &hostPathType,
+									Path: realProcPath,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// newReaderDaemonSet makes the daemon-set of the agent reading files in hostDirs only on nodes,
+// the directories are mounted read only and must exist on the nodes
+func newReaderDaemonSet(nodes []string, hostDirs []string) *appv1.DaemonSet {
+	labels := map[string]string{"app": readerName}
+	ds := &appv1.DaemonSet{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: readerName,
+		},
+		Spec: appv1.DaemonSetSpec{
+			UpdateStrategy: appv1.DaemonSetUpdateStrategy{
+				Type: appv1.RollingUpdateDaemonSetStrategyType,
+				RollingUpdate: &appv1.RollingUpdateDaemonSet{
+					MaxUnavailable: &max,
+				},
+			},
+			Selector: &meta_v1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name: name,
+						Env: []corev1.EnvVar{{
+							Name:  hostDirsEnv,
+							Value: strings.Join(hostDirs, ":"),
+						}},
+						Image:           imageName,
+						ImagePullPolicy: corev1.PullAlways,
+					}},
+					RestartPolicy: corev1.RestartPolicyAlways,
+					Tolerations: []corev1.Toleration{{
+						Operator: corev1.TolerationOpExists,
+					}},
+					Affinity: &corev1.Affinity{
+						NodeAffinity: &corev1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+								NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+									MatchFields: []corev1.NodeSelectorRequirement{{
+										Key:      "metadata.name",
+										Operator: corev1.NodeSelectorOpIn,
+										Values:   nodes,
+									}},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	spec := &ds.Spec.Template.Spec
+	for i, dir := range hostDirs {
+		volumeName := fmt.Sprintf("%s%d", hostDirName, i)
+		spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      volumeName,
+			MountPath: path.Join(hostRootPath, dir),
+			ReadOnly:  true,
+		})
+		spec.Volumes = append(spec.Volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Type: &hostPathType,
+					Path: dir,
+				},
+			},
+		})
+	}
+	return ds
+}
+
+// create deploys the daemon-set of s once, a daemon-set left by a previous run is used as it is
+func (k *krawler) create(s *agentSet) error {
+	s.createOnce.Do(func() {
+		ds, err := s.daemonSet()
+		if err != nil {
+			s.createErr = err
+			return
+		}
+		_, err = k.appClient.DaemonSets(defaultNamespace).Create(ds)
+		if err != nil && !errors.IsAlreadyExists(err) {
+			s.createErr = err
+			return
+		}
+		s.created = true
+	})
+	return s.createErr
+}
+
+// delete removes the daemon-sets which are deployed
+func (k *krawler) delete() {
+	for _, s := range []*agentSet{&k.kubelets, &k.readers} {
+		if s.created {
+			k.appClient.DaemonSets(defaultNamespace).Delete(s.name, nil)
+		}
+	}
+}
+
+// desiredPods waits until the daemon-set of kubelets knows how many pods it runs, until the timeout
+func (k *krawler) desiredPods() (int, error) {
+	if err := k.create(&k.kubelets); err != nil {
+		return 0, err
+	}
+	deadline := time.Now().Add(k.timeout)
+	for {
+		time.Sleep(time.Second / 2)
+
+		ds, err := k.appClient.DaemonSets(defaultNamespace).Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			return 0, err
+		}
+		if ds.Status.DesiredNumberScheduled > 0 {
+			return int(ds.Status.DesiredNumberScheduled), nil
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("daemon-set %s scheduled no pod in %s", name, k.timeout)
+		}
+	}
+}
+
+// runningPods returns running pods of the daemon-set of kubelets
+func (k *krawler) runningPods() ([]corev1.Pod, error) {
+	return k.setPods(&k.kubelets)
+}
+
+// setPods waits until every pod of the daemon-set of s is available, until the timeout, and returns running ones.
+// The others are kept in notRunning of s.
+func (k *krawler) setPods(s *agentSet) ([]corev1.Pod, error) {
+	s.podsOnce.Do(func() {
+		if s.podsErr = k.create(s); s.podsErr != nil {
+			return
+		}
+		deadline := time.Now().Add(k.timeout)
+		for {
+			time.Sleep(time.Second / 2)
+
+			ds, err := k.appClient.DaemonSets(defaultNamespace).Get(s.name, meta_v1.GetOptions{})
+			if err != nil {
+				s.podsErr = err
+				return
+			}
+			if ds.Status.NumberAvailable == ds.Status.DesiredNumberScheduled && ds.Status.DesiredNumberScheduled > 0 {
+				time.Sleep(time.Second)
+				break
+			}
+			if time.Now().After(deadline) {
+				break
+			}
+		}
+
+		pods, err := getPods(k.coreclient, defaultNamespace, fmt.Sprintf("app=%s", s.name))
+		if err != nil {
+			s.podsErr = err
+			return
+		}
+		for _, p := range pods.Items {
+			if p.Status.Phase == corev1.PodRunning {
+				s.pods = append(s.pods, p)
+			} else {
+				s.notRunning = append(s.notRunning, p)
+			}
+		}
+	})
+	return s.pods, s.podsErr
+}
+
+// notRunningCertifications are errors of kubelets on nodes whose agent did not run until the timeout
+func (k *krawler) notRunningCertifications() []serverCertification {
+	certs := []serverCertification{}
+	for i := range k.kubelets.notRunning {
+		p := &k.kubelets.notRunning[i]
+		certs = append(certs, serverCertification{
+			Entry: Entry{
+				Type: "kubelet",
+				Node: p.Spec.NodeName,
+				Name: "Error",
+			},
+			Warning: fmt.Sprintf("agent %s is not running in %s: %s", p.Name, k.timeout, podState(p)),
+		})
+	}
+	return certs
+}
+
+// podState describes the phase of a pod with the reason its container is waiting for, e.g. `Pending: ImagePullBackOff`
+func podState(p *corev1.Pod) string {
+	state := string(p.Status.Phase)
+	for _, c := range p.Status.ContainerStatuses {
+		if c.State.Waiting != nil && c.State.Waiting.Reason != "" {
+			return state + ": " + c.State.Waiting.Reason
+		}
+	}
+	return state
+}
+
+// reset forgets the pods of the daemon-sets to find them again for the next collection of a long running process
+func (k *krawler) reset() {
+	for _, s := range []*agentSet{&k.kubelets, &k.readers} {
+		s.podsOnce = sync.Once{}
+		s.pods, s.podsErr, s.notRunning = nil, nil, nil
+	}
+}
+
+// podOn returns the pod of the reader on the node
+func (k *krawler) podOn(node string) (*corev1.Pod, error) {
+	pods, err := k.setPods(&k.readers)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods {
+		if pods[i].Spec.NodeName == node {
+			return &pods[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no reader is running on node %s", node)
+}
+
+// readFile reads a file on the node of the pod by `cat` in the pod.
+// If the image of the pod has no cat, e.g. it is distroless, the reader on the node reads the file from the host instead,
+// only in the directories mounted to it.
+func (k *krawler) readFile(p *corev1.Pod, path string) (string, error) {
+	out, err := ExecPod(k.coreclient, kubesystemNamespace, p, []string{"cat", path}, k.errOut)
+	if err == nil {
+		return out, nil
+	}
+	if !strings.Contains(err.Error(), missingExecutable) {
+		return "", err
+	}
+
+	reader, readerErr := k.podOn(p.Spec.NodeName)
+	if readerErr == nil {
+		out, readerErr = ExecPod(k.coreclient, defaultNamespace, reader, []string{"krawler", krawlerReadFlag, path}, k.errOut)
+	}
+	if readerErr != nil {
+		return "", fmt.Errorf("%s, and the agent failed too: %s", err, readerErr)
+	}
+	return out, nil
+}
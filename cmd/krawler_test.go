@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsV1Client "k8s.io/client-go/kubernetes/typed/apps/v1"
+	coreV1Client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+func TestKrawlerDaemonSetMountsNoHostDirs(t *testing.T) {
+	spec := newKrawlerDaemonSet().Spec.Template.Spec
+	for _, v := range spec.Volumes {
+		assert.NotContains(t, v.Name, hostDirName)
+		assert.NotEqual(t, "/", v.HostPath.Path)
+	}
+	for _, e := range spec.Containers[0].Env {
+		assert.NotEqual(t, hostDirsEnv, e.Name)
+	}
+}
+
+func TestReaderDaemonSet(t *testing.T) {
+	ds := newReaderDaemonSet([]string{"master1", "master2"}, []string{"/etc/kubernetes", "/srv/pki"})
+	spec := ds.Spec.Template.Spec
+	assert.Equal(t, readerName, ds.Name)
+	assert.Equal(t, map[string]string{"app": readerName}, ds.Spec.Selector.MatchLabels)
+
+	// only on nodes of control plane
+	terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if assert.Equal(t, 1, len(terms)) {
+		assert.Equal(t, []corev1.NodeSelectorRequirement{{
+			Key:      "metadata.name",
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{"master1", "master2"},
+		}}, terms[0].MatchFields)
+	}
+
+	mounts := map[string]string{}
+	for _, m := range spec.Containers[0].VolumeMounts {
+		assert.True(t, m.ReadOnly, m.Name)
+		mounts[m.Name] = m.MountPath
+	}
+	assert.Equal(t, map[string]string{hostDirName + "0": "/host/etc/kubernetes", hostDirName + "1": "/host/srv/pki"}, mounts)
+
+	volumes := map[string]string{}
+	for _, v := range spec.Volumes {
+		// directories are never created on the host
+		assert.Equal(t, corev1.HostPathDirectory, *v.HostPath.Type)
+		volumes[v.Name] = v.HostPath.Path
+	}
+	assert.Equal(t, map[string]string{hostDirName + "0": "/etc/kubernetes", hostDirName + "1": "/srv/pki"}, volumes)
+
+	assert.Equal(t, []corev1.EnvVar{{Name: hostDirsEnv, Value: "/etc/kubernetes:/srv/pki"}}, spec.Containers[0].Env)
+}
+
+func TestPodHostDirs(t *testing.T) {
+	apiserver := corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName: "master1",
+			Containers: []corev1.Container{{
+				Command: []string{
+					"kube-apiserver",
+					"--client-ca-file=/etc/kubernetes/pki/ca.crt",
+					"--tls-cert-file=/pki/apiserver.crt",
+					"--tls-private-key-file=/pki/apiserver.key",
+					"--etcd-certfile=/etc/etcd/apiserver-etcd-client.crt",
+					"--audit-log-path=/var/log/audit.log",
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "k8s-certs", MountPath: "/etc/kubernetes/pki"},
+					{Name: "srv-pki", MountPath: "/pki/"},
+					{Name: "etcd-client", MountPath: "/etc/etcd/apiserver-etcd-client.crt"},
+					{Name: "audit", MountPath: "/var/log"},
+				},
+			}},
+			Volumes: []corev1.Volume{
+				{Name: "k8s-certs", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/etc/kubernetes/pki"}}},
+				{Name: "srv-pki", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/srv/kubernetes/pki"}}},
+				{Name: "etcd-client", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/srv/etcd/client.crt"}}},
+				{Name: "audit", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/log"}}},
+			},
+		},
+	}
+	scheduler := corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName: "master2",
+			Containers: []corev1.Container{{
+				Command: []string{"kube-scheduler", "--kubeconfig=/etc/kubernetes/scheduler.conf"},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "kubeconfig", MountPath: "/etc/kubernetes/scheduler.conf"},
+				},
+			}},
+			Volumes: []corev1.Volume{
+				{Name: "kubeconfig", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/etc/kubernetes/scheduler.conf"}}},
+			},
+		},
+	}
+
+	// only directories of files read by flags, the audit log is not
+	assert.ElementsMatch(t, []string{"/etc/kubernetes/pki", "/srv/kubernetes/pki", "/srv/kubernetes/pki", "/srv/etcd"}, podHostDirs([]corev1.Pod{apiserver}))
+
+	k := &krawler{}
+	k.mount(&corev1.PodList{Items: []corev1.Pod{apiserver}}, &corev1.PodList{Items: []corev1.Pod{scheduler}}, &corev1.PodList{})
+	assert.Equal(t, []string{"/etc/kubernetes", "/srv/etcd", "/srv/kubernetes/pki"}, k.hostDirs)
+	assert.Equal(t, []string{"master1", "master2"}, k.nodes)
+
+	// collections of a long running process do not grow them
+	k.mount(&corev1.PodList{Items: []corev1.Pod{apiserver}})
+	assert.Equal(t, []string{"/etc/kubernetes", "/srv/etcd", "/srv/kubernetes/pki"}, k.hostDirs)
+	assert.Equal(t, []string{"master1", "master2"}, k.nodes)
+
+	assert.Equal(t, []string{"/etc/kubernetes"}, topDirs([]string{"/", "relative", "/etc/kubernetes/"}))
+}
+
+// newTestKrawler makes a krawler with clients of a fake apiserver which has the daemon-set with status and pods
+func newTestKrawler(t *testing.T, status appv1.DaemonSetStatus, pods []corev1.Pod) (*krawler, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(r.URL.Path, "/daemonsets"):
+			json.NewEncoder(w).Encode(appv1.DaemonSet{
+				TypeMeta:   meta_v1.TypeMeta{Kind: "DaemonSet", APIVersion: "apps/v1"},
+				ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: defaultNamespace},
+				Status:     status,
+			})
+		case strings.HasSuffix(r.URL.Path, "/pods"):
+			json.NewEncoder(w).Encode(corev1.PodList{
+				TypeMeta: meta_v1.TypeMeta{Kind: "PodList", APIVersion: "v1"},
+				Items:    pods,
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	config := &rest.Config{Host: server.URL}
+	coreclient, err := coreV1Client.NewForConfig(config)
+	assert.NoError(t, err)
+	appClient, err := appsV1Client.NewForConfig(config)
+	assert.NoError(t, err)
+	k := newKrawler(coreclient, appClient, ioutil.Discard)
+	k.timeout = 0
+	return k, server.Close
+}
+
+func TestRunningPodsTimeout(t *testing.T) {
+	running := corev1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "krawler-a"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	pulling := corev1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "krawler-b"},
+		Spec:       corev1.PodSpec{NodeName: "node-2"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+			}},
+		},
+	}
+	k, stop := newTestKrawler(t, appv1.DaemonSetStatus{DesiredNumberScheduled: 2, NumberAvailable: 1}, []corev1.Pod{running, pulling})
+	defer stop()
+
+	desired, err := k.desiredPods()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, desired)
+
+	// the pod which never runs does not block the others
+	pods, err := k.runningPods()
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(pods)) {
+		assert.Equal(t, "node-1", pods[0].Spec.NodeName)
+	}
+
+	certs := k.notRunningCertifications()
+	if assert.Equal(t, 1, len(certs)) {
+		assert.Equal(t, Entry{Type: "kubelet", Node: "node-2", Name: "Error"}, certs[0].Entry)
+		assert.Equal(t, "agent krawler-b is not running in 0s: Pending: ImagePullBackOff", certs[0].Warning)
+	}
+
+	// readers are not deployed without nodes of control plane
+	_, err = k.podOn("node-1")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no node of control plane")
+	}
+
+	k.reset()
+	assert.Equal(t, 0, len(k.notRunningCertifications()))
+}
+
+func TestDesiredPodsTimeout(t *testing.T) {
+	k, stop := newTestKrawler(t, appv1.DaemonSetStatus{}, nil)
+	defer stop()
+
+	_, err := k.desiredPods()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "scheduled no pod")
+	}
+}
+
+func TestReaderPods(t *testing.T) {
+	reader := corev1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "krawler-reader-a"},
+		Spec:       corev1.PodSpec{NodeName: "master1"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	k, stop := newTestKrawler(t, appv1.DaemonSetStatus{DesiredNumberScheduled: 1, NumberAvailable: 1}, []corev1.Pod{reader})
+	defer stop()
+	k.mount(&corev1.PodList{Items: []corev1.Pod{{Spec: corev1.PodSpec{NodeName: "master1", Containers: []corev1.Container{{}}}}}})
+
+	p, err := k.podOn("master1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "krawler-reader-a", p.Name)
+	}
+	_, err = k.podOn("node-1")
+	assert.Error(t, err)
+
+	// kubelets are not deployed only to read files
+	assert.True(t, k.readers.created)
+	assert.False(t, k.kubelets.created)
+}
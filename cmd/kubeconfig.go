@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	kubeconfigEntryType = "kubeconfig"
+	kubeconfigUserName  = "user/"
+	kubeconfigCAName    = "cluster/"
+)
+
+var (
+	kubeconfigExample = `
+	# view expiration of certifications of every user and cluster in the kubeconfig in use
+	%[1]s check-cert kubeconfig
+
+	# view them in another kubeconfig
+	%[1]s check-cert kubeconfig --kubeconfig /etc/kubernetes/admin.conf
+`
+)
+
+// NewCmdKubeconfig provides a cobra command reading certifications of the kubeconfig of the user
+func NewCmdKubeconfig(o *ExpirationOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "kubeconfig [flags]",
+		Short:        "View expiration days of certifications of every user and cluster in the kubeconfig",
+		Example:      fmt.Sprintf(kubeconfigExample, "kubectl"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			o.setOutputFlagSpecified(c)
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return o.RunKubeconfig()
+		},
+	}
+
+	o.addReportFlags(cmd)
+
+	return cmd
+}
+
+// RunKubeconfig reads certifications of the kubeconfig resolved by the config flags like kubectl and reports them
+func (o *ExpirationOptions) RunKubeconfig() error {
+	config, err := o.configFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return err
+	}
+	if len(config.Clusters) == 0 && len(config.AuthInfos) == 0 {
+		return fmt.Errorf("no kubeconfig is found")
+	}
+
+	// paths in the config are already resolved to be absolute
+	certs := kubeconfigCertifications(&config, "", "", func(path string) (string, string, error) {
+		body, err := ioutil.ReadFile(path)
+		return path, string(body), err
+	})
+	o.audit(certs, nil)
+	sortCertifications(certs, defaultSort)
+	return o.report(certs)
+}
+
+// refReader reads a file a kubeconfig refers to by path and returns the path to report the file by with its content
+type refReader func(path string) (string, string, error)
+
+// kubeconfigCertifications makes certifications of the client certification of every user
+// and of the CA of every cluster in config read from path.
+// Embedded data of a merged config is reported by the file it came from instead of path.
+// Files the config refers to are read by readFile.
+func kubeconfigCertifications(config *clientcmdapi.Config, node string, path string, readFile refReader) []serverCertification {
+	clusterNames := []string{}
+	for name := range config.Clusters {
+		clusterNames = append(clusterNames, name)
+	}
+	sort.Strings(clusterNames)
+
+	certs := []serverCertification{}
+	roots := map[string]serverCertification{}
+	for _, name := range clusterNames {
+		cluster := config.Clusters[name]
+		var c []serverCertification
+		if len(cluster.CertificateAuthorityData) > 0 {
+			c = newCertifications(kubeconfigEntryType, node, kubeconfigCAName+name, originOf(cluster.LocationOfOrigin, path), string(cluster.CertificateAuthorityData), nil)
+		} else if cluster.CertificateAuthority != "" {
+			caPath, ca, err := readFile(cluster.CertificateAuthority)
+			c = newCertifications(kubeconfigEntryType, node, kubeconfigCAName+name, caPath, ca, err)
+		} else {
+			// the cluster is trusted by the system CAs or is insecure
+			continue
+		}
+		roots[name] = c[0]
+		certs = append(certs, c...)
+	}
+
+	userNames := []string{}
+	for name := range config.AuthInfos {
+		userNames = append(userNames, name)
+	}
+	sort.Strings(userNames)
+
+	for _, name := range userNames {
+		u := config.AuthInfos[name]
+		var (
+			c    []serverCertification
+			cert string
+			err  error
+		)
+		if len(u.ClientCertificateData) > 0 {
+			cert = string(u.ClientCertificateData)
+			c = newCertifications(kubeconfigEntryType, node, kubeconfigUserName+name, originOf(u.LocationOfOrigin, path), cert, nil)
+		} else if u.ClientCertificate != "" {
+			var certPath string
+			certPath, cert, err = readFile(u.ClientCertificate)
+			c = newCertifications(kubeconfigEntryType, node, kubeconfigUserName+name, certPath, cert, err)
+		} else {
+			// the user authenticates by a token or a plugin
+			continue
+		}
+
+		if len(u.ClientKeyData) > 0 {
+			checkKeyPair(c, cert, string(u.ClientKeyData), "client-key-data of user "+name+" in "+originOf(u.LocationOfOrigin, path), nil)
+		} else if u.ClientKey != "" {
+			keyPath, key, err := readFile(u.ClientKey)
+			checkKeyPair(c, cert, key, keyPath, err)
+		}
+
+		// the client certification is verified against the CA of the cluster of its first context
+		cluster := userCluster(config, name)
+		if ca, ok := roots[cluster]; ok && len(ca.certs) > 0 {
+			c[0].roots = ca.certs
+			c[0].caName = "certificate-authority of cluster " + cluster
+		}
+		certs = append(certs, c...)
+	}
+	return certs
+}
+
+// originOf is the file an entry of a kubeconfig came from, or path if it is not known
+func originOf(location string, path string) string {
+	if location != "" {
+		return location
+	}
+	return path
+}
+
+// userCluster finds the cluster of the first context of user by name, the current context first
+func userCluster(config *clientcmdapi.Config, user string) string {
+	if current, ok := config.Contexts[config.CurrentContext]; ok && current.AuthInfo == user {
+		return current.Cluster
+	}
+	names := []string{}
+	for name, c := range config.Contexts {
+		if c.AuthInfo == user {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return config.Contexts[names[0]].Cluster
+}
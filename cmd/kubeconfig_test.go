@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestKubeconfigCertifications(t *testing.T) {
+	due := time.Now().Add(24 * time.Hour * 100).UTC().Truncate(time.Second)
+	ca := newTestCA(t, "kubernetes", time.Now().Add(24*time.Hour*3650), nil)
+	admin := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "kubernetes-admin"}, NotBefore: time.Now(), NotAfter: due}, ca)
+	other := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "other"}, NotBefore: time.Now(), NotAfter: due}, nil)
+
+	files := map[string]string{
+		"/etc/kubernetes/pki/ca.crt": ca.pem,
+		"admin.crt":                  admin.pem,
+		"admin.key":                  other.keyPem,
+	}
+	readFile := func(path string) (string, string, error) {
+		if body, ok := files[path]; ok {
+			return path, body, nil
+		}
+		return path, "", fmt.Errorf("open %s: no such file or directory", path)
+	}
+
+	config := &clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"kubernetes": {Server: "https://10.0.0.1:6443", CertificateAuthority: "/etc/kubernetes/pki/ca.crt"},
+			"public":     {Server: "https://example.com"},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"kubernetes-admin": {ClientCertificate: "admin.crt", ClientKey: "admin.key"},
+			"other":            {ClientCertificateData: []byte(other.pem), ClientKeyData: []byte(other.keyPem)},
+			"token":            {Token: "secret"},
+			"missing":          {ClientCertificate: "missing.crt"},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"admin": {Cluster: "kubernetes", AuthInfo: "kubernetes-admin"},
+			"other": {Cluster: "public", AuthInfo: "other"},
+		},
+		CurrentContext: "admin",
+	}
+
+	certs := kubeconfigCertifications(config, "master1", "/root/.kube/config", readFile)
+
+	assert.Equal(t, 4, len(certs))
+	assert.Equal(t, kubeconfigEntryType, certs[0].Entry.Type)
+	assert.Equal(t, "cluster/kubernetes", certs[0].Entry.Name)
+	assert.Equal(t, "/etc/kubernetes/pki/ca.crt", certs[0].Entry.Path)
+	assert.Equal(t, "master1", certs[0].Entry.Node)
+
+	assert.Equal(t, "user/kubernetes-admin", certs[1].Entry.Name)
+	assert.Equal(t, "admin.crt", certs[1].Entry.Path)
+	assert.Equal(t, due, certs[1].Entry.Due)
+	assert.Contains(t, certs[1].Warning, "private key admin.key does not match the certification")
+	assert.Equal(t, ca.cert, certs[1].roots[0])
+	assert.Equal(t, "certificate-authority of cluster kubernetes", certs[1].caName)
+
+	assert.Equal(t, "user/missing", certs[2].Entry.Name)
+	assert.True(t, certs[2].Entry.Due.IsZero())
+	assert.Contains(t, certs[2].Warning, "missing.crt")
+
+	assert.Equal(t, "user/other", certs[3].Entry.Name)
+	assert.Equal(t, "/root/.kube/config", certs[3].Entry.Path)
+	assert.Equal(t, "", certs[3].Warning)
+	assert.Equal(t, 0, len(certs[3].roots))
+}
+
+func TestRunKubeconfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	due := time.Now().Add(24 * time.Hour * 100).UTC().Truncate(time.Second)
+	ca := newTestCA(t, "kubernetes", time.Now().Add(24*time.Hour*3650), nil)
+	admin := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "kubernetes-admin"}, NotBefore: time.Now(), NotAfter: due}, ca)
+	writeTestFile(t, filepath.Join(dir, "pki/admin.crt"), admin.pem)
+	writeTestFile(t, filepath.Join(dir, "pki/admin.key"), admin.keyPem)
+	kubeconfig := filepath.Join(dir, "config")
+	writeTestFile(t, kubeconfig, `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority-data: `+base64.StdEncoding.EncodeToString([]byte(ca.pem))+`
+    server: https://10.0.0.1:6443
+  name: kubernetes
+contexts:
+- context:
+    cluster: kubernetes
+    user: kubernetes-admin
+  name: kubernetes-admin@kubernetes
+current-context: kubernetes-admin@kubernetes
+users:
+- name: kubernetes-admin
+  user:
+    client-certificate: pki/admin.crt
+    client-key: pki/admin.key
+`)
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewExpirationOptions(streams)
+	*o.configFlags.KubeConfig = kubeconfig
+	*o.printFlags.OutputFormat = "json"
+	assert.NoError(t, o.validateOutput())
+
+	assert.NoError(t, o.RunKubeconfig())
+
+	list := struct {
+		Items []serverCertification `json:"items"`
+	}{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &list))
+	assert.Equal(t, 2, len(list.Items))
+	assert.Equal(t, "cluster/kubernetes", list.Items[0].Entry.Name)
+	assert.Equal(t, kubeconfig, list.Items[0].Entry.Path)
+	// relative paths are relative to the kubeconfig
+	assert.Equal(t, "user/kubernetes-admin", list.Items[1].Entry.Name)
+	assert.Equal(t, filepath.Join(dir, "pki/admin.crt"), list.Items[1].Entry.Path)
+	assert.Equal(t, due, list.Items[1].Entry.Due)
+	assert.Equal(t, statusOK, list.Items[1].Status)
+	assert.Equal(t, "", list.Items[1].Warning)
+
+	writeTestFile(t, filepath.Join(dir, "empty"), "apiVersion: v1\nkind: Config\n")
+	o = NewExpirationOptions(genericclioptions.NewTestIOStreamsDiscard())
+	*o.configFlags.KubeConfig = filepath.Join(dir, "empty")
+	assert.Error(t, o.RunKubeconfig())
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	fileEntryType = "file"
+	// maxScanFileSize is the size of the largest file read while scanning, certifications and kubeconfigs are small
+	maxScanFileSize = 1 << 20
+)
+
+var (
+	localExample = `
+	# view expiration of every certification and kubeconfig on a node without apiserver
+	%[1]s check-cert local
+
+	# view them in the whole filesystem
+	%[1]s check-cert local --dir /
+
+	# view them in a copy of the root of a node taken as ./node1
+	%[1]s check-cert local --root ./node1 --node-name node1
+`
+
+	// defaultLocalDirs are scanned under the root if no directory is given, they have certifications of kubernetes
+	defaultLocalDirs = []string{"etc/kubernetes", "var/lib/kubelet/pki"}
+
+	// skipDirs under the root are virtual filesystems, trust stores of the system and layers of container images
+	// which have no certifications of kubernetes but many CAs which may be expired
+	skipDirs = []string{"proc", "sys", "dev", "run", "etc/ssl", "usr/share/ca-certificates", "var/lib/docker", "var/lib/containerd"}
+
+	pemCertificateHeader = []byte("-----BEGIN CERTIFICATE-----")
+)
+
+// LocalOptions provides information to scan certifications in a local filesystem without apiserver
+type LocalOptions struct {
+	*ExpirationOptions
+
+	root     string
+	dir      string
+	nodeName string
+}
+
+// NewCmdLocal provides a cobra command scanning certifications in a local filesystem
+func NewCmdLocal(o *ExpirationOptions) *cobra.Command {
+	hostname, _ := os.Hostname()
+	l := &LocalOptions{
+		ExpirationOptions: o,
+		root:              "/",
+		nodeName:          hostname,
+	}
+
+	cmd := &cobra.Command{
+		Use:          "local [flags]",
+		Short:        "View expiration days of certifications and kubeconfigs in a local filesystem without apiserver",
+		Example:      fmt.Sprintf(localExample, "kubectl"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			o.setOutputFlagSpecified(c)
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return l.Run()
+		},
+	}
+
+	cmd.Flags().StringVar(&l.root, "root", l.root, "root of the filesystem, absolute paths in kubeconfigs are read under it")
+	cmd.Flags().StringVar(&l.dir, "dir", l.dir, "directory to scan, etc/kubernetes and var/lib/kubelet/pki under the root if it is empty")
+	cmd.Flags().StringVar(&l.nodeName, "node-name", l.nodeName, "node name of the certifications")
+	o.addReportFlags(cmd)
+
+	return cmd
+}
+
+// Run scans certifications and reports them
+func (l *LocalOptions) Run() error {
+	certs := []serverCertification{}
+	for _, dir := range l.scanDirs() {
+		certs = append(certs, scanDir(dir, l.root, l.nodeName)...)
+	}
+	l.audit(certs, nil)
+	sortCertifications(certs, defaultSort)
+	return l.report(certs)
+}
+
+// scanDirs are --dir or the default directories under the root which exist on the node.
+// If none of them exists, all of them are scanned to report it.
+func (l *LocalOptions) scanDirs() []string {
+	if l.dir != "" {
+		return []string{l.dir}
+	}
+	all, found := []string{}, []string{}
+	for _, d := range defaultLocalDirs {
+		dir := filepath.Join(l.root, d)
+		all = append(all, dir)
+		if _, err := os.Stat(dir); err == nil {
+			found = append(found, dir)
+		}
+	}
+	if len(found) == 0 {
+		return all
+	}
+	return found
+}
+
+// scanDir walks dir and reads every PEM certification and kubeconfig in it.
+// Absolute paths in kubeconfigs are read under root.
+func scanDir(dir string, root string, nodeName string) []serverCertification {
+	certs := []serverCertification{}
+	skip := map[string]bool{}
+	for _, d := range skipDirs {
+		skip[filepath.Join(root, d)] = true
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			// unreadable files are not certifications of the node, e.g. sockets or files of other users
+			return nil
+		}
+		if info.IsDir() {
+			// the directory given to scan is scanned even if it is skipped under the root
+			if skip[path] && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Size() > maxScanFileSize {
+			return nil
+		}
+		body, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		certs = append(certs, scanFile(path, filepath.Base(path), body, nodeName, func(p string) (string, string, error) {
+			return readLocalFile(root, filepath.Dir(path), p)
+		})...)
+		return nil
+	})
+	if err != nil {
+		certs = append(certs, serverCertification{
+			Entry: Entry{
+				Type: fileEntryType,
+				Node: nodeName,
+				Name: "Error",
+				Path: dir,
+			},
+			Warning: err.Error(),
+		})
+	}
+	return certs
+}
+
+// scanFile makes certifications of body of a file at path if it is a kubeconfig or has PEM certifications.
+// Certifications are named by name of the file, and files a kubeconfig refers to are read by readFile.
+func scanFile(path string, name string, body []byte, nodeName string, readFile refReader) []serverCertification {
+	if config, ok := parseKubeconfig(body); ok {
+		return kubeconfigCertifications(config, nodeName, path, readFile)
+	}
+	if !bytes.Contains(body, pemCertificateHeader) {
+		return nil
+	}
+
+	certs := newCertifications(fileEntryType, nodeName, name, path, string(body), nil)
+	// e.g. kubelet-client-2020-01-02-15-04-05.pem of kubelet has the key in the same file,
+	// kubelet-client-current.pem is a symlink to it which is not scanned
+	if bytes.Contains(body, []byte("PRIVATE KEY-----")) {
+		checkKeyPair(certs, string(body), string(body), path, nil)
+		return certs
+	}
+	// candidates are relative to the directory of the file like paths in kubeconfigs
+	for _, keyFile := range keyFileCandidates(name) {
+		keyPath, key, err := readFile(keyFile)
+		if err != nil {
+			continue
+		}
+		checkKeyPair(certs, string(body), key, keyPath, nil)
+		break
+	}
+	return certs
+}
+
+// parseKubeconfig parses body if it is a kubeconfig with any cluster or user
+func parseKubeconfig(body []byte) (*clientcmdapi.Config, bool) {
+	if !bytes.Contains(body, []byte("clusters")) && !bytes.Contains(body, []byte("users")) {
+		return nil, false
+	}
+	config, err := clientcmd.Load(body)
+	if err != nil || (len(config.Clusters) == 0 && len(config.AuthInfos) == 0) {
+		return nil, false
+	}
+	return config, true
+}
+
+// keyFileCandidates are names of the private key of a certification file by the conventions of kubeadm and kubespray,
+// e.g. apiserver.key of apiserver.crt and admin-key.pem of admin.pem
+func keyFileCandidates(name string) []string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	switch ext {
+	case ".crt", ".cert":
+		return []string{base + ".key"}
+	case ".pem":
+		if strings.HasSuffix(base, "-key") {
+			return nil
+		}
+		return []string{base + "-key.pem", base + ".key"}
+	}
+	return nil
+}
+
+// readLocalFile reads a file a kubeconfig in dir refers to, absolute paths are read under root.
+// Absolute paths are reported as they are because they are paths on the node.
+func readLocalFile(root string, dir string, path string) (string, string, error) {
+	local := filepath.Join(dir, path)
+	if filepath.IsAbs(path) {
+		local = filepath.Join(root, path)
+	}
+	body, err := ioutil.ReadFile(local)
+	if err != nil {
+		return path, "", err
+	}
+	if filepath.IsAbs(path) {
+		return path, string(body), nil
+	}
+	return local, string(body), nil
+}
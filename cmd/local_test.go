@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	due := time.Now().Add(100 * 24 * time.Hour).UTC().Truncate(time.Second)
+	ca := newTestCA(t, "kubernetes", time.Now().Add(3650*24*time.Hour), nil)
+	apiserver := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "kube-apiserver"}, NotBefore: time.Now(), NotAfter: due}, ca)
+	kubelet := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "system:node:master1"}, NotBefore: time.Now(), NotAfter: due}, ca)
+	renewed := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "kube-apiserver"}, NotBefore: time.Now(), NotAfter: due}, ca)
+
+	writeTestFile(t, filepath.Join(root, "etc/kubernetes/pki/ca.crt"), ca.pem)
+	writeTestFile(t, filepath.Join(root, "etc/kubernetes/pki/apiserver.crt"), apiserver.pem)
+	writeTestFile(t, filepath.Join(root, "etc/kubernetes/pki/apiserver.key"), renewed.keyPem)
+	writeTestFile(t, filepath.Join(root, "etc/kubernetes/pki/sa.pub"), "-----BEGIN PUBLIC KEY-----\n")
+	writeTestFile(t, filepath.Join(root, "var/lib/kubelet/pki/kubelet-client-current.pem"), kubelet.pem+kubelet.keyPem)
+	writeTestFile(t, filepath.Join(root, "etc/kubernetes/kubelet.conf"), `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority-data: `+base64.StdEncoding.EncodeToString([]byte(ca.pem))+`
+    server: https://10.0.0.1:6443
+  name: kubernetes
+contexts:
+- context:
+    cluster: kubernetes
+    user: system:node:master1
+  name: default
+current-context: default
+users:
+- name: system:node:master1
+  user:
+    client-certificate: /var/lib/kubelet/pki/kubelet-client-current.pem
+    client-key: /var/lib/kubelet/pki/kubelet-client-current.pem
+`)
+	// virtual filesystems, trust stores and layers of images are not scanned
+	expired := newTestCA(t, "expired root", time.Now().Add(-24*time.Hour), nil)
+	writeTestFile(t, filepath.Join(root, "proc/1/cert.pem"), ca.pem)
+	writeTestFile(t, filepath.Join(root, "etc/ssl/certs/ca-certificates.crt"), ca.pem+expired.pem)
+	writeTestFile(t, filepath.Join(root, "var/lib/containerd/layer/etc/ssl/certs/ca-certificates.crt"), expired.pem)
+
+	certs := scanDir(root, root, "master1")
+
+	assert.Equal(t, 5, len(certs))
+	byName := map[string]serverCertification{}
+	for _, c := range certs {
+		byName[c.Entry.Name] = c
+	}
+
+	assert.Equal(t, fileEntryType, byName["apiserver.crt"].Entry.Type)
+	assert.Equal(t, "master1", byName["apiserver.crt"].Entry.Node)
+	assert.Equal(t, filepath.Join(root, "etc/kubernetes/pki/apiserver.crt"), byName["apiserver.crt"].Entry.Path)
+	assert.Equal(t, due, byName["apiserver.crt"].Entry.Due)
+	assert.Contains(t, byName["apiserver.crt"].Warning, "private key "+filepath.Join(root, "etc/kubernetes/pki/apiserver.key")+" does not match")
+
+	assert.Equal(t, "", byName["ca.crt"].Warning)
+	assert.Equal(t, "", byName["kubelet-client-current.pem"].Warning)
+
+	assert.Equal(t, kubeconfigEntryType, byName["cluster/kubernetes"].Entry.Type)
+	assert.Equal(t, filepath.Join(root, "etc/kubernetes/kubelet.conf"), byName["cluster/kubernetes"].Entry.Path)
+	user := byName["user/system:node:master1"]
+	assert.Equal(t, "/var/lib/kubelet/pki/kubelet-client-current.pem", user.Entry.Path)
+	assert.Equal(t, due, user.Entry.Due)
+	assert.Equal(t, "", user.Warning)
+	assert.Equal(t, ca.cert, user.roots[0])
+}
+
+func TestScanDirs(t *testing.T) {
+	root, err := ioutil.TempDir("", "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	l := &LocalOptions{root: root}
+	// nothing to scan is reported by errors of the default directories
+	assert.Equal(t, []string{filepath.Join(root, "etc/kubernetes"), filepath.Join(root, "var/lib/kubelet/pki")}, l.scanDirs())
+
+	// e.g. a worker node has no /etc/kubernetes/pki but kubelet certifications
+	writeTestFile(t, filepath.Join(root, "var/lib/kubelet/pki/kubelet.crt"), "")
+	assert.Equal(t, []string{filepath.Join(root, "var/lib/kubelet/pki")}, l.scanDirs())
+
+	l.dir = "/"
+	assert.Equal(t, []string{"/"}, l.scanDirs())
+
+	// a skipped directory is scanned if it is given
+	ca := newTestCA(t, "kubernetes", time.Now().Add(24*time.Hour), nil)
+	writeTestFile(t, filepath.Join(root, "etc/ssl/certs/ca.crt"), ca.pem)
+	assert.Equal(t, 0, len(scanDir(root, root, "master1")))
+	assert.Equal(t, 1, len(scanDir(filepath.Join(root, "etc/ssl"), root, "master1")))
+}
+
+func TestScanDirWithoutDir(t *testing.T) {
+	certs := scanDir("/not/exists", "/not/exists", "master1")
+
+	assert.Equal(t, 1, len(certs))
+	assert.Equal(t, "Error", certs[0].Entry.Name)
+	assert.Equal(t, "/not/exists", certs[0].Entry.Path)
+}
+
+func TestKeyFileCandidates(t *testing.T) {
+	assert.Equal(t, []string{"apiserver.key"}, keyFileCandidates("apiserver.crt"))
+	assert.Equal(t, []string{"admin-node1-key.pem", "admin-node1.key"}, keyFileCandidates("admin-node1.pem"))
+	assert.Equal(t, 0, len(keyFileCandidates("admin-node1-key.pem")))
+	assert.Equal(t, 0, len(keyFileCandidates("ca.srl")))
+}
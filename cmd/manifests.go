@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	defaultManifestsDir = "/etc/kubernetes/manifests"
+	manifestEntryType   = "manifest"
+)
+
+var (
+	manifestsExample = `
+	# view expiration of control plane certifications by static pod manifests on a control plane node, even if apiserver is down
+	%[1]s check-cert manifests
+
+	# view them in a copy of /etc/kubernetes of a node taken as ./node1/etc/kubernetes
+	%[1]s check-cert manifests --root ./node1 --node-name node1
+`
+)
+
+// ManifestsOptions provides information to read certifications by static pod manifests without apiserver
+type ManifestsOptions struct {
+	*ExpirationOptions
+
+	dir      string
+	root     string
+	nodeName string
+}
+
+// localReader reads files of pods in a local directory which is the root of the node
+type localReader struct {
+	root string
+}
+
+func (r localReader) readFile(p *corev1.Pod, path string) (string, error) {
+	body, err := ioutil.ReadFile(filepath.Join(r.root, filepath.Clean("/"+path)))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// NewCmdManifests provides a cobra command reading certifications by static pod manifests
+func NewCmdManifests(o *ExpirationOptions) *cobra.Command {
+	hostname, _ := os.Hostname()
+	m := &ManifestsOptions{
+		ExpirationOptions: o,
+		root:              "/",
+		nodeName:          hostname,
+	}
+
+	cmd := &cobra.Command{
+		Use:          "manifests [flags]",
+		Short:        "View expiration days of control plane certifications by static pod manifests without apiserver",
+		Example:      fmt.Sprintf(manifestsExample, "kubectl"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			o.setOutputFlagSpecified(c)
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return m.Run()
+		},
+	}
+
+	cmd.Flags().StringVar(&m.dir, "dir", m.dir, "directory of static pod manifests, /etc/kubernetes/manifests under --root if it is empty")
+	cmd.Flags().StringVar(&m.root, "root", m.root, "directory which paths in manifests are relative to, e.g. a copy of the root of a node")
+	cmd.Flags().StringVar(&m.nodeName, "node-name", m.nodeName, "node name of the certifications")
+	o.addReportFlags(cmd)
+
+	return cmd
+}
+
+// Run reads certifications by every manifest and reports them
+func (m *ManifestsOptions) Run() error {
+	certs := collectManifests(m.manifestsDir(), localReader{root: m.root}, m.nodeName)
+	m.audit(certs, nil)
+	sortCertifications(certs, defaultSort)
+	return m.report(certs)
+}
+
+// manifestsDir is the directory of static pod manifests given by --dir or the default one under the root
+func (m *ManifestsOptions) manifestsDir() string {
+	if m.dir != "" {
+		return m.dir
+	}
+	return filepath.Join(m.root, defaultManifestsDir)
+}
+
+// manifestCollectors read certifications of a control plane component by the value of its `component` label
+var manifestCollectors = map[string]func(r fileReader, p *corev1.Pod) []serverCertification{
+	"kube-apiserver": apiserverCertifications,
+	"etcd":           etcdCertifications,
+	"kube-controller-manager": func(r fileReader, p *corev1.Pod) []serverCertification {
+		return componentKubeconfigCertifications(r, p, "controller-manager")
+	},
+	"kube-scheduler": func(r fileReader, p *corev1.Pod) []serverCertification {
+		return componentKubeconfigCertifications(r, p, "scheduler")
+	},
+}
+
+// collectManifests reads certifications of control plane components by static pod manifests in dir
+func collectManifests(dir string, r fileReader, nodeName string) []serverCertification {
+	errorResult := func(path string, err error) []serverCertification {
+		return []serverCertification{{
+			Entry: Entry{
+				Type: manifestEntryType,
+				Node: nodeName,
+				Name: "Error",
+				Path: path,
+			},
+			Warning: err.Error(),
+		}}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return errorResult(dir, err)
+	}
+	names := []string{}
+	for _, f := range files {
+		switch strings.ToLower(filepath.Ext(f.Name())) {
+		case ".yaml", ".yml", ".json":
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+
+	certs := []serverCertification{}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		p, err := readManifest(path)
+		if err != nil {
+			certs = append(certs, errorResult(path, err)...)
+			continue
+		}
+		collector, ok := manifestCollectors[manifestComponent(p)]
+		if !ok {
+			continue
+		}
+		p.Spec.NodeName = nodeName
+		certs = append(certs, collector(r, p)...)
+	}
+	return certs
+}
+
+func readManifest(path string) (*corev1.Pod, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &corev1.Pod{}
+	if err := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(body), len(body)).Decode(p); err != nil {
+		return nil, err
+	}
+	if len(p.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("no container in the manifest")
+	}
+	return p, nil
+}
+
+// manifestComponent tells the component of a static pod by its label, or by the name of its container
+func manifestComponent(p *corev1.Pod) string {
+	if c, ok := p.Labels["component"]; ok {
+		return c
+	}
+	return p.Spec.Containers[0].Name
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func writeTestFile(t *testing.T, path string, body string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectManifests(t *testing.T) {
+	root, err := ioutil.TempDir("", "manifests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	due := time.Now().Add(100 * 24 * time.Hour).UTC().Truncate(time.Second)
+	apiserver := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "apiserver"}, NotBefore: time.Now(), NotAfter: due}, nil)
+	scheduler := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "system:kube-scheduler"}, NotBefore: time.Now(), NotAfter: due}, nil)
+
+	writeTestFile(t, filepath.Join(root, "etc/kubernetes/pki/apiserver.crt"), apiserver.pem)
+	writeTestFile(t, filepath.Join(root, "etc/kubernetes/pki/apiserver.key"), apiserver.keyPem)
+	writeTestFile(t, filepath.Join(root, "etc/kubernetes/scheduler.conf"), `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://10.0.0.1:6443
+  name: kubernetes
+contexts:
+- context:
+    cluster: kubernetes
+    user: system:kube-scheduler
+  name: default
+current-context: default
+users:
+- name: system:kube-scheduler
+  user:
+    client-certificate-data: `+base64.StdEncoding.EncodeToString([]byte(scheduler.pem))+`
+    client-key-data: `+base64.StdEncoding.EncodeToString([]byte(scheduler.keyPem))+`
+`)
+
+	dir := filepath.Join(root, "etc/kubernetes/manifests")
+	writeTestFile(t, filepath.Join(dir, "kube-apiserver.yaml"), `apiVersion: v1
+kind: Pod
+metadata:
+  name: kube-apiserver
+  labels:
+    component: kube-apiserver
+spec:
+  containers:
+  - name: kube-apiserver
+    command:
+    - kube-apiserver
+    - --tls-cert-file=/etc/kubernetes/pki/apiserver.crt
+    - --tls-private-key-file=/etc/kubernetes/pki/apiserver.key
+    - --client-ca-file=/etc/kubernetes/pki/ca.crt
+`)
+	writeTestFile(t, filepath.Join(dir, "kube-scheduler.yaml"), `apiVersion: v1
+kind: Pod
+metadata:
+  name: kube-scheduler
+spec:
+  containers:
+  - name: kube-scheduler
+    command:
+    - kube-scheduler
+    - --kubeconfig=/etc/kubernetes/scheduler.conf
+`)
+	writeTestFile(t, filepath.Join(dir, "haproxy.yaml"), `apiVersion: v1
+kind: Pod
+metadata:
+  name: haproxy
+spec:
+  containers:
+  - name: haproxy
+`)
+	writeTestFile(t, filepath.Join(dir, "broken.yaml"), "spec: [")
+	writeTestFile(t, filepath.Join(dir, "README"), "not a manifest")
+
+	certs := collectManifests(dir, localReader{root: root}, "master1")
+
+	assert.Equal(t, 4, len(certs))
+	assert.Equal(t, manifestEntryType, certs[0].Entry.Type)
+	assert.Equal(t, filepath.Join(dir, "broken.yaml"), certs[0].Entry.Path)
+
+	assert.Equal(t, "apiserver", certs[1].Entry.Type)
+	assert.Equal(t, "master1", certs[1].Entry.Node)
+	assert.Equal(t, tlsCertFlag, certs[1].Entry.Name)
+	assert.Equal(t, due, certs[1].Entry.Due)
+	assert.Equal(t, "", certs[1].Warning)
+
+	// the CA is not in the root
+	assert.Equal(t, "client-ca-file", certs[2].Entry.Name)
+	assert.True(t, certs[2].Entry.Due.IsZero())
+
+	assert.Equal(t, "scheduler", certs[3].Entry.Type)
+	assert.Equal(t, "client-cert", certs[3].Entry.Name)
+	assert.Equal(t, due, certs[3].Entry.Due)
+	assert.Equal(t, "", certs[3].Warning)
+}
+
+func TestCollectManifestsWithoutDir(t *testing.T) {
+	certs := collectManifests("/not/exists", localReader{root: "/"}, "master1")
+
+	assert.Equal(t, 1, len(certs))
+	assert.Equal(t, "Error", certs[0].Entry.Name)
+}
+
+func TestManifestsDir(t *testing.T) {
+	cmd := NewCmdManifests(NewExpirationOptions(genericclioptions.NewTestIOStreamsDiscard()))
+	assert.Equal(t, "", cmd.Flags().Lookup("dir").DefValue)
+	assert.Equal(t, "/", cmd.Flags().Lookup("root").DefValue)
+
+	m := &ManifestsOptions{root: "/"}
+	assert.Equal(t, "/etc/kubernetes/manifests", m.manifestsDir())
+
+	// the default follows --root
+	m.root = "./node1"
+	assert.Equal(t, "node1/etc/kubernetes/manifests", m.manifestsDir())
+
+	// --dir is used as it is
+	m.dir = "/srv/manifests"
+	assert.Equal(t, "/srv/manifests", m.manifestsDir())
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cast"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+const (
+	tableOutput             = ""
+	wideOutput              = "wide"
+	csvOutput               = "csv"
+	customColumnsOutput     = "custom-columns"
+	customColumnsFileOutput = "custom-columns-file"
+
+	// bump the version whenever a field of CertificationList changes incompatibly
+	certificationListAPIVersion = "check-cert/v1"
+	certificationListKind       = "CertificationList"
+)
+
+// CertificationList is the versioned document written by machine readable outputs
+type CertificationList struct {
+	meta_v1.TypeMeta `json:",inline"`
+
+	Items []serverCertification `json:"items"`
+}
+
+// NewCertificationList wraps certifications with the current schema version
+func NewCertificationList(items []serverCertification) *CertificationList {
+	return &CertificationList{
+		TypeMeta: meta_v1.TypeMeta{
+			APIVersion: certificationListAPIVersion,
+			Kind:       certificationListKind,
+		},
+		Items: items,
+	}
+}
+
+// DeepCopyObject implements runtime.Object so that kubectl printers can be used
+func (l *CertificationList) DeepCopyObject() runtime.Object {
+	out := *l
+	out.Items = make([]serverCertification, len(l.Items))
+	copy(out.Items, l.Items)
+	for i := range out.Items {
+		if d := out.Items[i].Entry.Details; d != nil {
+			details := *d
+			details.DNSNames = append([]string(nil), d.DNSNames...)
+			details.IPAddresses = append([]string(nil), d.IPAddresses...)
+			out.Items[i].Entry.Details = &details
+		}
+	}
+	return &out
+}
+
+func newPrintFlags() *genericclioptions.PrintFlags {
+	outputFormat := ""
+	return &genericclioptions.PrintFlags{
+		OutputFormat:         &outputFormat,
+		JSONYamlPrintFlags:   genericclioptions.NewJSONYamlPrintFlags(),
+		TemplatePrinterFlags: genericclioptions.NewKubeTemplatePrintFlags(),
+	}
+}
+
+// outputFormats returns every format accepted by --output
+func (o *ExpirationOptions) outputFormats() []string {
+	return append([]string{wideOutput, csvOutput, customColumnsOutput, customColumnsFileOutput}, o.printFlags.AllowedFormats()...)
+}
+
+// outputFormat splits --output into the format name and its argument (e.g. a template)
+func (o *ExpirationOptions) outputFormat() (string, string) {
+	s := strings.SplitN(*o.printFlags.OutputFormat, "=", 2)
+	if len(s) == 1 {
+		return s[0], ""
+	}
+	return s[0], s[1]
+}
+
+func (o *ExpirationOptions) validateOutput() error {
+	format, arg := o.outputFormat()
+	switch format {
+	case tableOutput, wideOutput, csvOutput:
+		return nil
+	case customColumnsOutput, customColumnsFileOutput:
+		_, err := newCustomColumnsPrinter(format, arg)
+		return err
+	}
+	_, err := o.printFlags.ToPrinter()
+	if genericclioptions.IsNoCompatiblePrinterError(err) {
+		return fmt.Errorf("unknown output format %q, allowed formats are: %s", *o.printFlags.OutputFormat, strings.Join(o.outputFormats(), ","))
+	}
+	return err
+}
+
+func (o *ExpirationOptions) printCertifications(certs []serverCertification) error {
+	if o.summary {
+		return o.printSummary(summarize(certs))
+	}
+
+	format, arg := o.outputFormat()
+	if !o.details && format != wideOutput {
+		certs = withoutDetails(certs)
+	}
+
+	switch format {
+	case tableOutput:
+		if o.details {
+			return printWideTable(o.Out, certs)
+		}
+		return printTable(o.Out, certs)
+	case wideOutput:
+		return printWideTable(o.Out, certs)
+	case csvOutput:
+		return printCSV(o.Out, certs)
+	case customColumnsOutput, customColumnsFileOutput:
+		p, err := newCustomColumnsPrinter(format, arg)
+		if err != nil {
+			return err
+		}
+		return p.print(o.Out, certs)
+	}
+
+	p, err := o.printFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	return p.PrintObj(NewCertificationList(certs), o.Out)
+}
+
+func printTable(out io.Writer, certs []serverCertification) error {
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Type", "Node", "Name", "Status", "Remaining", "Due", "Path", "Warning"})
+
+	for _, v := range certs {
+		m := []string{v.Entry.Type, v.Entry.Node, displayName(v.Entry), v.Status, displayRemaining(v), v.Entry.Due.String(), v.Entry.Path, v.Warning}
+		table.Append(m)
+	}
+	table.Render() // Send output
+
+	return nil
+}
+
+// printWideTable prints details of certificates too
+func printWideTable(out io.Writer, certs []serverCertification) error {
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Type", "Node", "Name", "Status", "Remaining", "Due", "Path", "Warning",
+		"Subject", "Issuer", "SANs", "Serial", "Key", "Signature", "Not Before", "SHA-256 Fingerprint"})
+
+	for _, v := range certs {
+		m := []string{v.Entry.Type, v.Entry.Node, displayName(v.Entry), v.Status, displayRemaining(v), v.Entry.Due.String(), v.Entry.Path, v.Warning}
+		if d := v.Entry.Details; d != nil {
+			m = append(m, d.Subject, d.Issuer, strings.Join(append(append([]string{}, d.DNSNames...), d.IPAddresses...), ","), d.SerialNumber,
+				fmt.Sprintf("%s %d", d.KeyAlgorithm, d.KeySize), d.SignatureAlgorithm, d.NotBefore.String(), d.Fingerprint)
+		} else {
+			m = append(m, "", "", "", "", "", "", "", "")
+		}
+		table.Append(m)
+	}
+	table.Render() // Send output
+
+	return nil
+}
+
+// withoutDetails returns certifications without details unless they are asked
+func withoutDetails(certs []serverCertification) []serverCertification {
+	result := make([]serverCertification, len(certs))
+	for i, v := range certs {
+		v.Entry.Details = nil
+		result[i] = v
+	}
+	return result
+}
+
+// displayRemaining shows the remaining time of a certification, nothing if it could not be collected
+func displayRemaining(v serverCertification) string {
+	if v.Entry.Due.IsZero() {
+		return ""
+	}
+	return formatRemaining(time.Duration(v.RemainingSeconds) * time.Second)
+}
+
+// displayName shows the position of a certificate in its bundle next to the name
+func displayName(e Entry) string {
+	if e.Position == 0 {
+		return e.Name
+	}
+	return fmt.Sprintf("%s [%d %s]", e.Name, e.Position, e.Role)
+}
+
+func printCSV(out io.Writer, certs []serverCertification) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"type", "node", "name", "days", "due", "path", "warning", "status", "position", "role", "remaining_seconds"}); err != nil {
+		return err
+	}
+
+	for _, v := range certs {
+		due := ""
+		if !v.Entry.Due.IsZero() {
+			due = v.Entry.Due.Format(time.RFC3339)
+		}
+		position := ""
+		if v.Entry.Position > 0 {
+			position = cast.ToString(v.Entry.Position)
+		}
+		remaining := ""
+		if !v.Entry.Due.IsZero() {
+			remaining = cast.ToString(v.RemainingSeconds)
+		}
+		m := []string{v.Entry.Type, v.Entry.Node, v.Entry.Name, cast.ToString(v.Entry.Days), due, v.Entry.Path, v.Warning, v.Status, position, v.Entry.Role, remaining}
+		if err := w.Write(m); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func testCertifications() []serverCertification {
+	return []serverCertification{{
+		Entry: Entry{
+			Type: "apiserver",
+			Node: "master",
+			Name: "tls-cert-file",
+			Days: 10,
+			Due:  time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Path: "/etc/kubernetes/pki/apiserver.crt",
+		},
+		Status:           statusOK,
+		Warning:          "",
+		RemainingSeconds: 864000,
+	}, {
+		Entry: Entry{
+			Type: "kubelet",
+			Node: "node, 1",
+			Name: "server-cert",
+			Path: "/var/lib/kubelet/pki/kubelet.crt",
+		},
+		Status:  statusUnknown,
+		Warning: "Can be ignored this.",
+	}}
+}
+
+func printWithOutput(t *testing.T, output string, certs []serverCertification) string {
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewExpirationOptions(streams)
+	*o.printFlags.OutputFormat = output
+
+	assert.NoError(t, o.validateOutput())
+	assert.NoError(t, o.printCertifications(certs))
+	return out.String()
+}
+
+func TestPrintJSON(t *testing.T) {
+	out := printWithOutput(t, "json", testCertifications()[:1])
+
+	assert.JSONEq(t, `{
+    "apiVersion": "check-cert/v1",
+    "kind": "CertificationList",
+    "items": [{
+        "entry": {"type": "apiserver", "node": "master", "name": "tls-cert-file", "days": 10, "due": "2019-01-01T00:00:00Z", "path": "/etc/kubernetes/pki/apiserver.crt"},
+        "status": "OK",
+        "warning": "",
+        "remainingSeconds": 864000
+    }]
+}`, out)
+}
+
+func TestPrintYAML(t *testing.T) {
+	out := printWithOutput(t, "yaml", testCertifications()[:1])
+
+	assert.Contains(t, out, "apiVersion: check-cert/v1\n")
+	assert.Contains(t, out, "    name: tls-cert-file\n")
+}
+
+func TestPrintCSV(t *testing.T) {
+	out := printWithOutput(t, "csv", testCertifications())
+
+	assert.Equal(t, "type,node,name,days,due,path,warning,status,position,role,remaining_seconds\n"+
+		"apiserver,master,tls-cert-file,10,2019-01-01T00:00:00Z,/etc/kubernetes/pki/apiserver.crt,,OK,,,864000\n"+
+		"kubelet,\"node, 1\",server-cert,0,,/var/lib/kubelet/pki/kubelet.crt,Can be ignored this.,UNKNOWN,,,\n", out)
+}
+
+func TestPrintJSONPath(t *testing.T) {
+	out := printWithOutput(t, `jsonpath={range .items[*]}{.entry.node}={.entry.days};{end}`, testCertifications())
+
+	assert.Equal(t, "master=10;node, 1=0;", out)
+}
+
+func TestPrintGoTemplate(t *testing.T) {
+	out := printWithOutput(t, `go-template={{range .items}}{{.entry.name}} {{end}}`, testCertifications())
+
+	assert.Equal(t, "tls-cert-file server-cert ", out)
+}
+
+func TestPrintCustomColumns(t *testing.T) {
+	out := printWithOutput(t, "custom-columns=NODE:.entry.node,DAYS:{.entry.days},MISSING:.entry.nothing", testCertifications())
+
+	assert.Equal(t, "NODE      DAYS      MISSING\n"+
+		"master    10        <none>\n"+
+		"node, 1   0         <none>\n", out)
+}
+
+func TestUnknownOutput(t *testing.T) {
+	o := NewExpirationOptions(genericclioptions.NewTestIOStreamsDiscard())
+
+	*o.printFlags.OutputFormat = "xml"
+	assert.Error(t, o.validateOutput())
+
+	*o.printFlags.OutputFormat = "custom-columns=NODE"
+	assert.Error(t, o.validateOutput())
+}
+
+func TestPrintDetails(t *testing.T) {
+	certs := testCertifications()[:1]
+	certs[0].Entry.Details = &Details{Subject: "CN=kube-apiserver", DNSNames: []string{"kubernetes"}}
+
+	out := printWithOutput(t, "jsonpath={.items[0].entry.details.subject}", certs)
+	assert.Equal(t, "", out)
+
+	out = printWithOutput(t, "wide", certs)
+	assert.Contains(t, out, "CN=kube-apiserver")
+
+	streams, _, buffer, _ := genericclioptions.NewTestIOStreams()
+	o := NewExpirationOptions(streams)
+	o.details = true
+	*o.printFlags.OutputFormat = "jsonpath={.items[0].entry.details.subject}"
+	assert.NoError(t, o.printCertifications(certs))
+	assert.Equal(t, "CN=kube-apiserver", buffer.String())
+}
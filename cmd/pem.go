@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// roles of a certificate in a PEM bundle
+const (
+	leafRole         = "leaf"
+	intermediateRole = "intermediate"
+	rootRole         = "root"
+)
+
+// GetCertificatesFromPEM takes PEM data and parses every certificate in it
+func GetCertificatesFromPEM(cert string) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	rest := []byte(cert)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		// e.g. a private key next to the certificate
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %s", err.Error())
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("failed to parse certificate PEM")
+	}
+
+	return certs, nil
+}
+
+// newCertifications makes a certification from PEM data or a warning from the error happened while reading it.
+// A bundle is reported by its earliest expiry followed by every certificate with its position in the bundle.
+func newCertifications(entryType string, node string, name string, path string, cert string, err error) []serverCertification {
+	e := Entry{
+		Type: entryType,
+		Node: node,
+		Name: name,
+		Path: path,
+	}
+
+	var certs []*x509.Certificate
+	if err == nil {
+		certs, err = GetCertificatesFromPEM(cert)
+	}
+	if err != nil {
+		return []serverCertification{{
+			Entry:   e,
+			Warning: err.Error(),
+		}}
+	}
+
+	earliest := earliestCertificate(certs)
+	e.Due = earliest.NotAfter
+	e.Days = daysUntil(e.Due)
+	e.Details = NewDetails(earliest)
+	result := []serverCertification{{
+		Entry:   e,
+		Warning: "",
+		certs:   certs,
+	}}
+	if len(certs) == 1 {
+		return result
+	}
+
+	for i, c := range certs {
+		ce := e
+		ce.Due = c.NotAfter
+		ce.Days = daysUntil(c.NotAfter)
+		ce.Position = i + 1
+		ce.Role = chainRole(c)
+		ce.Details = NewDetails(c)
+		result = append(result, serverCertification{
+			Entry:   ce,
+			Warning: "",
+		})
+	}
+	return result
+}
+
+// chainRole tells whether a certificate is a self signed root, an intermediate CA or a leaf
+func chainRole(c *x509.Certificate) string {
+	if bytes.Equal(c.RawSubject, c.RawIssuer) && c.CheckSignatureFrom(c) == nil {
+		return rootRole
+	}
+	if c.IsCA {
+		return intermediateRole
+	}
+	return leafRole
+}
+
+func earliestCertificate(certs []*x509.Certificate) *x509.Certificate {
+	earliest := certs[0]
+	for _, c := range certs[1:] {
+		if c.NotAfter.Before(earliest.NotAfter) {
+			earliest = c
+		}
+	}
+	return earliest
+}
+
+// NewDetails describes a certificate
+func NewDetails(c *x509.Certificate) *Details {
+	ips := []string{}
+	for _, ip := range c.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	fingerprint := sha256.Sum256(c.Raw)
+
+	return &Details{
+		Subject:            c.Subject.String(),
+		Issuer:             c.Issuer.String(),
+		DNSNames:           c.DNSNames,
+		IPAddresses:        ips,
+		SerialNumber:       colonHex(c.SerialNumber.Bytes()),
+		KeyAlgorithm:       c.PublicKeyAlgorithm.String(),
+		KeySize:            keySize(c.PublicKey),
+		SignatureAlgorithm: c.SignatureAlgorithm.String(),
+		NotBefore:          c.NotBefore,
+		Fingerprint:        colonHex(fingerprint[:]),
+	}
+}
+
+func keySize(publicKey interface{}) int {
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	}
+	return 0
+}
+
+// colonHex formats bytes like openssl, e.g. 0A:1B
+func colonHex(b []byte) string {
+	s := make([]string, len(b))
+	for i := range b {
+		s[i] = fmt.Sprintf("%02X", b[i])
+	}
+	return strings.Join(s, ":")
+}
+
+func daysUntil(t time.Time) int {
+	return daysBetween(time.Now(), t)
+}
+
+// daysBetween floors the days so that a certification expired hours ago is -1 day, not 0
+func daysBetween(from time.Time, t time.Time) int {
+	return int(math.Floor(t.Sub(from).Hours() / 24))
+}
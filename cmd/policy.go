@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// minRSAKeySize is the smallest RSA key which is not weak
+const minRSAKeySize = 2048
+
+var sha1SignatureAlgorithms = map[x509.SignatureAlgorithm]bool{
+	x509.SHA1WithRSA:   true,
+	x509.DSAWithSHA1:   true,
+	x509.ECDSAWithSHA1: true,
+}
+
+// auditCrypto adds warnings of weak cryptography to certifications.
+// Validity longer than maxValidityDays is only checked for certificates which are not CA and if it is positive.
+func auditCrypto(certs []serverCertification, maxValidityDays int) {
+	for i := range certs {
+		v := &certs[i]
+		if v.Entry.Position != 0 {
+			continue
+		}
+		for _, c := range v.certs {
+			for _, w := range weakCrypto(c, maxValidityDays) {
+				// tell which certificate of a bundle is weak
+				if len(v.certs) > 1 {
+					w = fmt.Sprintf("%q %s", c.Subject.CommonName, w)
+				}
+				v.addWarning(w)
+			}
+		}
+	}
+}
+
+// weakCrypto returns findings about the signature, the key and the validity of a certificate
+func weakCrypto(c *x509.Certificate, maxValidityDays int) []string {
+	findings := []string{}
+	if sha1SignatureAlgorithms[c.SignatureAlgorithm] {
+		findings = append(findings, fmt.Sprintf("weak signature %s", c.SignatureAlgorithm))
+	}
+	if k, ok := c.PublicKey.(*rsa.PublicKey); ok && k.N.BitLen() < minRSAKeySize {
+		findings = append(findings, fmt.Sprintf("weak RSA key of %d bits", k.N.BitLen()))
+	}
+	if maxValidityDays > 0 && !c.IsCA {
+		if days := int(c.NotAfter.Sub(c.NotBefore) / (24 * time.Hour)); days > maxValidityDays {
+			findings = append(findings, fmt.Sprintf("valid for %d days longer than %d days", days, maxValidityDays))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeakCrypto(t *testing.T) {
+	now := time.Now()
+	c := newTestCertificate(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "leaf"},
+		NotBefore: now,
+		NotAfter:  now.Add(500 * 24 * time.Hour),
+	}, nil).cert
+
+	assert.Equal(t, []string{}, weakCrypto(c, 0))
+	assert.Equal(t, []string{}, weakCrypto(c, 825))
+	assert.Equal(t, []string{"valid for 500 days longer than 398 days"}, weakCrypto(c, 398))
+
+	ca := newTestCA(t, "ca", now.Add(10*365*24*time.Hour), nil).cert
+	assert.Equal(t, []string{}, weakCrypto(ca, 398))
+}
+
+func TestWeakCryptoOfRSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(1),
+		Subject:            pkix.Name{CommonName: "legacy"},
+		NotBefore:          time.Now(),
+		NotAfter:           time.Now().Add(24 * time.Hour),
+		SignatureAlgorithm: x509.SHA1WithRSA,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"weak signature SHA1-RSA", "weak RSA key of 1024 bits"}, weakCrypto(c, 0))
+}
+
+func TestAuditCryptoOfBundle(t *testing.T) {
+	now := time.Now()
+	long := newTestCertificate(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "long"},
+		NotBefore: now,
+		NotAfter:  now.Add(500 * 24 * time.Hour),
+	}, nil)
+	short := newTestCertificate(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "short"},
+		NotBefore: now,
+		NotAfter:  now.Add(100 * 24 * time.Hour),
+	}, nil)
+
+	certs := newCertifications("secret", "", "tls.crt", "ns/name:tls.crt", short.pem+long.pem, nil)
+	auditCrypto(certs, 398)
+
+	assert.Equal(t, `"long" valid for 500 days longer than 398 days`, certs[0].Warning)
+	assert.Equal(t, "", certs[1].Warning)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreV1Client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const kubernetesServiceName = "kubernetes"
+
+// sanTargets are names which clients use to reach serving certifications
+type sanTargets struct {
+	// nodeAddresses are addresses of Node.status.addresses by node name
+	nodeAddresses map[string][]string
+	// apiserverNames are names of apiserver shared by every node, e.g. the ClusterIP of the kubernetes service
+	apiserverNames []string
+}
+
+// collectSANTargets finds addresses of nodes, the ClusterIP of the kubernetes service and the host of the kubeconfig in use
+func collectSANTargets(coreclient *coreV1Client.CoreV1Client, server string) (*sanTargets, error) {
+	nodes, err := coreclient.Nodes().List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	t := &sanTargets{nodeAddresses: map[string][]string{}}
+	for _, n := range nodes.Items {
+		for _, a := range n.Status.Addresses {
+			t.nodeAddresses[n.Name] = append(t.nodeAddresses[n.Name], a.Address)
+		}
+	}
+
+	svc, err := coreclient.Services(defaultNamespace).Get(kubernetesServiceName, meta_v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if svc.Spec.ClusterIP != "" {
+		t.apiserverNames = append(t.apiserverNames, svc.Spec.ClusterIP)
+	}
+
+	// e.g. the endpoint of a load balancer in front of apiservers
+	if u, err := url.Parse(server); err == nil && u.Hostname() != "" {
+		t.apiserverNames = append(t.apiserverNames, u.Hostname())
+	}
+	return t, nil
+}
+
+// auditSANs adds warnings to serving certifications of apiserver and kubelets whose SANs miss names clients use
+func auditSANs(certs []serverCertification, t *sanTargets) {
+	for i := range certs {
+		v := &certs[i]
+		if v.Entry.Position != 0 || len(v.certs) == 0 {
+			continue
+		}
+
+		var names []string
+		switch {
+		case v.Entry.Type == "apiserver" && v.Entry.Name == tlsCertFlag:
+			names = append(append(names, t.nodeAddresses[v.Entry.Node]...), t.apiserverNames...)
+		case v.Entry.Type == "kubelet" && v.Entry.Name == "server-cert":
+			names = t.nodeAddresses[v.Entry.Node]
+		default:
+			continue
+		}
+
+		if missing := missingSANs(v.certs[0].VerifyHostname, names); len(missing) > 0 {
+			v.addWarning(fmt.Sprintf("SANs do not cover %s", strings.Join(missing, ",")))
+		}
+	}
+}
+
+// missingSANs returns names which verify does not accept, sorted without duplicates
+func missingSANs(verify func(string) error, names []string) []string {
+	seen := map[string]bool{}
+	missing := []string{}
+	for _, n := range names {
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		if verify(n) != nil {
+			missing = append(missing, n)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServingCert(t *testing.T, dnsNames []string, ips []string) string {
+	template := &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "serving"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(24 * time.Hour),
+		DNSNames:  dnsNames,
+	}
+	for _, ip := range ips {
+		template.IPAddresses = append(template.IPAddresses, net.ParseIP(ip))
+	}
+	return newTestCertificate(t, template, nil).pem
+}
+
+func TestAuditSANs(t *testing.T) {
+	targets := &sanTargets{
+		nodeAddresses: map[string][]string{
+			"master1": {"10.0.0.1", "master1"},
+			"node1":   {"10.0.0.2", "node1"},
+		},
+		apiserverNames: []string{"10.96.0.1", "api.example.com"},
+	}
+
+	certs := append(
+		newCertifications("apiserver", "master1", tlsCertFlag, "apiserver.crt",
+			newTestServingCert(t, []string{"master1", "*.example.com"}, []string{"10.0.0.1", "10.96.0.1"}), nil),
+		newCertifications("apiserver", "master1", "kubelet-client-certificate", "apiserver-kubelet-client.crt",
+			newTestServingCert(t, nil, nil), nil)...)
+	certs = append(certs, newCertifications("kubelet", "node1", "server-cert", "kubelet.crt",
+		newTestServingCert(t, []string{"node1"}, nil), nil)...)
+	certs = append(certs, newCertifications("kubelet", "unknown", "server-cert", "kubelet.crt",
+		newTestServingCert(t, nil, nil), nil)...)
+
+	auditSANs(certs, targets)
+
+	assert.Equal(t, "", certs[0].Warning)
+	assert.Equal(t, "", certs[1].Warning)
+	assert.Equal(t, "SANs do not cover 10.0.0.2", certs[2].Warning)
+	assert.Equal(t, "", certs[3].Warning)
+}
+
+func TestMissingSANs(t *testing.T) {
+	verify := func(n string) error {
+		if n == "covered" {
+			return nil
+		}
+		return assert.AnError
+	}
+
+	assert.Equal(t, []string{"a", "b"}, missingSANs(verify, []string{"b", "covered", "a", "b", ""}))
+	assert.Equal(t, []string{}, missingSANs(verify, []string{"covered"}))
+}
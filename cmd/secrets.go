@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	coreV1Client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	secretEntryType = "secret"
+	secretCAKey     = "ca.crt"
+)
+
+// secretCertKeys are keys of a TLS secret which can hold certifications, ca.crt is optional
+var secretCertKeys = []string{corev1.TLSCertKey, secretCAKey}
+
+// collectSecrets reads certifications of TLS secrets in namespaces or in all namespaces if none is given
+func collectSecrets(coreclient *coreV1Client.CoreV1Client, namespaces []string) []serverCertification {
+	if len(namespaces) == 0 {
+		namespaces = []string{meta_v1.NamespaceAll}
+	}
+
+	certs := []serverCertification{}
+	for _, namespace := range namespaces {
+		secrets, err := coreclient.Secrets(namespace).List(meta_v1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("type", string(corev1.SecretTypeTLS)).String(),
+		})
+		if err != nil {
+			certs = append(certs, serverCertification{
+				Entry: Entry{
+					Type: secretEntryType,
+					Name: "Error",
+					Path: namespace,
+				},
+				Warning: err.Error(),
+			})
+			continue
+		}
+
+		for _, secret := range secrets.Items {
+			certs = append(certs, secretCertifications(&secret)...)
+		}
+	}
+
+	return certs
+}
+
+// secretCertifications makes certifications of every certification key in the secret
+func secretCertifications(secret *corev1.Secret) []serverCertification {
+	certs := []serverCertification{}
+	for _, key := range secretCertKeys {
+		data, ok := secret.Data[key]
+		if !ok {
+			continue
+		}
+		path := fmt.Sprintf("%s/%s:%s", secret.Namespace, secret.Name, key)
+		c := newCertifications(secretEntryType, "", key, path, string(data), nil)
+		if tlsKey, ok := secret.Data[corev1.TLSPrivateKeyKey]; ok && key == corev1.TLSCertKey {
+			checkKeyPair(c, string(data), string(tlsKey), fmt.Sprintf("%s/%s:%s", secret.Namespace, secret.Name, corev1.TLSPrivateKeyKey), nil)
+		}
+		certs = append(certs, c...)
+	}
+	return certs
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSecretCertifications(t *testing.T) {
+	due := time.Now().Add(24 * time.Hour * 100).UTC().Truncate(time.Second)
+	secret := &corev1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Namespace: "ingress",
+			Name:      "web",
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey: []byte(newTestCert(t, "web", time.Now(), due)),
+			secretCAKey:       []byte("broken"),
+		},
+	}
+
+	certs := secretCertifications(secret)
+
+	assert.Equal(t, 2, len(certs))
+	assert.Equal(t, "secret", certs[0].Entry.Type)
+	assert.Equal(t, "tls.crt", certs[0].Entry.Name)
+	assert.Equal(t, "ingress/web:tls.crt", certs[0].Entry.Path)
+	assert.Equal(t, due, certs[0].Entry.Due)
+	assert.Equal(t, 99, certs[0].Entry.Days)
+	assert.Equal(t, "", certs[0].Warning)
+
+	assert.Equal(t, "ingress/web:ca.crt", certs[1].Entry.Path)
+	assert.Equal(t, "failed to parse certificate PEM", certs[1].Warning)
+}
+
+func TestSecretCertificationsWithMismatchedKey(t *testing.T) {
+	due := time.Now().Add(24 * time.Hour * 100)
+	web := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "web"}, NotBefore: time.Now(), NotAfter: due}, nil)
+	renewed := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "web"}, NotBefore: time.Now(), NotAfter: due}, nil)
+	secret := &corev1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Namespace: "ingress",
+			Name:      "web",
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte(renewed.pem),
+			corev1.TLSPrivateKeyKey: []byte(web.keyPem),
+		},
+	}
+
+	certs := secretCertifications(secret)
+
+	assert.Equal(t, 1, len(certs))
+	assert.Contains(t, certs[0].Warning, "private key ingress/web:tls.key does not match the certification")
+	assert.NotContains(t, certs[0].Warning, "PRIVATE KEY")
+
+	secret.Data[corev1.TLSPrivateKeyKey] = []byte(renewed.keyPem)
+	assert.Equal(t, "", secretCertifications(secret)[0].Warning)
+}
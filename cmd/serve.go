@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/log"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultListenAddress   = ":9793"
+	defaultCollectInterval = 10 * time.Minute
+	metricsPath            = "/metrics"
+)
+
+var (
+	serveExample = `
+	# expose expiration of certifications about control plane on :9793/metrics
+	%[1]s check-cert serve
+
+	# also expose kubelet certifications and collect them every hour
+	%[1]s check-cert serve --also-check-kubelet --interval 1h --listen :9100
+`
+)
+
+// ServeOptions provides information for the metrics exporter
+type ServeOptions struct {
+	*ExpirationOptions
+
+	listen   string
+	interval time.Duration
+
+	expiry           *prometheus.GaugeVec
+	collectionErrors prometheus.Counter
+	lastCollection   prometheus.Gauge
+
+	// collector gathers certifications for every update
+	collector func() ([]serverCertification, error)
+}
+
+// NewServeOptions provides an instance of ServeOptions with default values
+func NewServeOptions(o *ExpirationOptions) *ServeOptions {
+	return &ServeOptions{
+		ExpirationOptions: o,
+		listen:            defaultListenAddress,
+		interval:          defaultCollectInterval,
+		expiry: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kube_cert_expiry_timestamp_seconds",
+			Help: "Expiration of the certification in seconds since epoch.",
+		}, []string{"type", "node", "name", "path"}),
+		collectionErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "kube_cert_collection_errors_total",
+			Help: "Number of failed collections including certifications which could not be read.",
+		}),
+		lastCollection: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kube_cert_last_collection_timestamp_seconds",
+			Help: "Time of the last collection in seconds since epoch.",
+		}),
+	}
+}
+
+// NewCmdServe provides a cobra command exporting certifications as prometheus metrics
+func NewCmdServe(o *ExpirationOptions) *cobra.Command {
+	s := NewServeOptions(o)
+
+	cmd := &cobra.Command{
+		Use:          "serve [flags]",
+		Short:        "Expose expiration of certifications in kubernetes cluster as prometheus metrics",
+		Example:      fmt.Sprintf(serveExample, "kubectl"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := s.Validate(); err != nil {
+				return err
+			}
+			if err := s.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&s.listen, "listen", s.listen, "address to expose metrics on")
+	cmd.Flags().DurationVar(&s.interval, "interval", s.interval, "interval between collections")
+
+	return cmd
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (s *ServeOptions) Validate() error {
+	if s.interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	return nil
+}
+
+// Run collects certifications periodically and serves them until the server fails or the process is stopped.
+// The agents of kubelets are kept between collections and removed when the server ends.
+func (s *ServeOptions) Run() error {
+	// nobody watches the progress of a daemon
+	s.progress = false
+
+	k, err := s.newKrawler()
+	if err != nil {
+		return err
+	}
+	defer k.delete()
+	s.collector = func() ([]serverCertification, error) {
+		k.reset()
+		return s.collectWith(k)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(s.expiry, s.collectionErrors, s.lastCollection)
+
+	go func() {
+		for {
+			s.update()
+			time.Sleep(s.interval)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Addr: s.listen, Handler: mux}
+	log.Infof("Listening on %s%s", s.listen, metricsPath)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case sig := <-stop:
+		log.Infof("Stopping by %s", sig)
+		return server.Close()
+	}
+}
+
+// update replaces the metrics by the certifications of a new collection
+func (s *ServeOptions) update() {
+	serverCertifications, err := s.collector()
+	if err != nil {
+		log.Errorf("Failed to collect certifications: %s", err)
+		s.collectionErrors.Inc()
+		return
+	}
+
+	s.expiry.Reset()
+	for _, v := range serverCertifications {
+		if v.Entry.Due.IsZero() {
+			log.Errorf("Failed to collect %s %s on %s: %s", v.Entry.Type, v.Entry.Name, v.Entry.Node, v.Warning)
+			s.collectionErrors.Inc()
+			continue
+		}
+		// a bundle is exported once by its earliest expiry to keep series unique
+		if v.Entry.Position > 0 {
+			continue
+		}
+		s.expiry.WithLabelValues(v.Entry.Type, v.Entry.Node, v.Entry.Name, v.Entry.Path).Set(float64(v.Entry.Due.Unix()))
+	}
+	s.lastCollection.SetToCurrentTime()
+	log.Infof("Collected %d certifications", len(serverCertifications))
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// countSeries counts the series collected from c
+func countSeries(c prometheus.Collector) int {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+	n := 0
+	for range ch {
+		n++
+	}
+	return n
+}
+
+func TestUpdate(t *testing.T) {
+	due := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	bundle := Entry{Type: "apiserver", Node: "master", Name: "client-ca-file", Path: "/etc/kubernetes/pki/ca.crt", Due: due}
+	position := bundle
+	position.Position, position.Due = 1, due.Add(time.Hour)
+
+	s := NewServeOptions(NewExpirationOptions(genericclioptions.NewTestIOStreamsDiscard()))
+	certs := []serverCertification{
+		{Entry: Entry{Type: "apiserver", Node: "master", Name: "tls-cert-file", Path: "/etc/kubernetes/pki/apiserver.crt", Due: due}},
+		{Entry: bundle},
+		{Entry: position},
+		{Entry: Entry{Type: "kubelet", Node: "node1", Name: "Error"}, Warning: "no agent is running on node node1"},
+	}
+	var collectErr error
+	s.collector = func() ([]serverCertification, error) {
+		return certs, collectErr
+	}
+
+	s.update()
+
+	assert.Equal(t, 2, countSeries(s.expiry))
+	assert.Equal(t, float64(due.Unix()), testutil.ToFloat64(s.expiry.WithLabelValues("apiserver", "master", "tls-cert-file", "/etc/kubernetes/pki/apiserver.crt")))
+	// a bundle is exported by its earliest expiry only
+	assert.Equal(t, float64(due.Unix()), testutil.ToFloat64(s.expiry.WithLabelValues("apiserver", "master", "client-ca-file", "/etc/kubernetes/pki/ca.crt")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.collectionErrors))
+	assert.True(t, testutil.ToFloat64(s.lastCollection) > 0)
+
+	// series of certifications which are gone are removed
+	certs = certs[:1]
+	s.update()
+	assert.Equal(t, 1, countSeries(s.expiry))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.collectionErrors))
+
+	// metrics of the last collection are kept when a collection fails
+	collectErr = fmt.Errorf("apiserver is down")
+	s.update()
+	assert.Equal(t, 1, countSeries(s.expiry))
+	assert.Equal(t, float64(2), testutil.ToFloat64(s.collectionErrors))
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// nagios plugin states
+const (
+	statusOK       = "OK"
+	statusWarning  = "WARNING"
+	statusCritical = "CRITICAL"
+	statusUnknown  = "UNKNOWN"
+	// expired certifications are critical for nagios but told apart from the ones about to expire
+	statusExpired = "EXPIRED"
+)
+
+var (
+	exitCodes = map[string]int{
+		statusOK:       0,
+		statusWarning:  1,
+		statusCritical: 2,
+		statusUnknown:  3,
+		statusExpired:  2,
+	}
+
+	// the worst status decides the exit code
+	severities = map[string]int{
+		statusOK:       0,
+		statusUnknown:  1,
+		statusWarning:  2,
+		statusCritical: 3,
+		statusExpired:  4,
+	}
+)
+
+// ExitError is returned when the check has to end with a non zero exit code
+type ExitError struct {
+	Code    int
+	Message string
+}
+
+func (e *ExitError) Error() string {
+	return e.Message
+}
+
+// formatRemaining shows a remaining time in days and hours like 3d4h, or hours and minutes within a day
+func formatRemaining(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	days := d / (24 * time.Hour)
+	hours := d % (24 * time.Hour) / time.Hour
+	minutes := d % time.Hour / time.Minute
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%s%dd%dh", sign, days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%s%dh%dm", sign, hours, minutes)
+	}
+	return fmt.Sprintf("%s%dm", sign, minutes)
+}
+
+// classify sets the status, the remaining days and time of every certification at now
+func classify(certs []serverCertification, warnDays int, criticalDays int, now time.Time) {
+	for i := range certs {
+		if !certs[i].Entry.Due.IsZero() {
+			certs[i].Entry.Days = daysBetween(now, certs[i].Entry.Due)
+			certs[i].RemainingSeconds = int64(certs[i].Entry.Due.Sub(now) / time.Second)
+		}
+		certs[i].Status = statusOf(certs[i].Entry, warnDays, criticalDays, now)
+	}
+}
+
+func statusOf(e Entry, warnDays int, criticalDays int, now time.Time) string {
+	// entries without due date could not be collected
+	if e.Due.IsZero() {
+		return statusUnknown
+	}
+	if !now.Before(e.Due) {
+		return statusExpired
+	}
+	// a certification which is not valid yet fails as much as an expired one
+	if e.Details != nil && now.Before(e.Details.NotBefore) {
+		return statusCritical
+	}
+	if e.Days < criticalDays {
+		return statusCritical
+	}
+	if e.Days < warnDays {
+		return statusWarning
+	}
+	return statusOK
+}
+
+// checkResult returns an ExitError for the worst status of certifications or nil when all are OK.
+// A bundle is counted by its certificates, not by its entry of the earliest expiry as well.
+func checkResult(certs []serverCertification) error {
+	bundles := map[string]bool{}
+	for _, v := range certs {
+		if v.Entry.Position > 0 {
+			bundles[bundleKey(v.Entry)] = true
+		}
+	}
+
+	worst := statusOK
+	counts := map[string]int{}
+	for _, v := range certs {
+		if v.Entry.Position == 0 && bundles[bundleKey(v.Entry)] {
+			continue
+		}
+		counts[v.Status]++
+		if severities[v.Status] > severities[worst] {
+			worst = v.Status
+		}
+	}
+	if worst == statusOK {
+		return nil
+	}
+
+	summary := []string{}
+	for _, s := range []string{statusExpired, statusCritical, statusWarning, statusUnknown} {
+		if counts[s] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+	return &ExitError{
+		Code:    exitCodes[worst],
+		Message: fmt.Sprintf("%s: %s certification(s)", worst, strings.Join(summary, ", ")),
+	}
+}
+
+func bundleKey(e Entry) string {
+	return strings.Join([]string{e.Type, e.Node, e.Name, e.Path}, "/")
+}
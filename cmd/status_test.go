@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestClassify(t *testing.T) {
+	now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	certs := []serverCertification{
+		{Entry: Entry{Due: now.Add(100*day + time.Hour)}},
+		{Entry: Entry{Due: now.Add(29*day + time.Hour)}},
+		{Entry: Entry{Due: now.Add(6*day + time.Hour)}},
+		{Entry: Entry{Due: now.Add(-9 * time.Hour)}},
+		{Entry: Entry{}},
+	}
+
+	classify(certs, 30, 7, now)
+
+	assert.Equal(t, statusOK, certs[0].Status)
+	assert.Equal(t, statusWarning, certs[1].Status)
+	assert.Equal(t, statusCritical, certs[2].Status)
+	assert.Equal(t, statusExpired, certs[3].Status)
+	assert.Equal(t, statusUnknown, certs[4].Status)
+
+	assert.Equal(t, 100, certs[0].Entry.Days)
+	assert.Equal(t, -1, certs[3].Entry.Days)
+	assert.Equal(t, int64(100*24*3600+3600), certs[0].RemainingSeconds)
+	assert.Equal(t, int64(-9*3600), certs[3].RemainingSeconds)
+	assert.Equal(t, int64(0), certs[4].RemainingSeconds)
+
+	// without thresholds only expired certifications are not OK
+	classify(certs, 0, 0, now)
+
+	assert.Equal(t, statusOK, certs[1].Status)
+	assert.Equal(t, statusOK, certs[2].Status)
+	assert.Equal(t, statusExpired, certs[3].Status)
+}
+
+func TestClassifyNotYetValid(t *testing.T) {
+	certs := []serverCertification{
+		{Entry: Entry{Days: 100, Due: time.Now().Add(100 * 24 * time.Hour), Details: &Details{NotBefore: time.Now().Add(time.Hour)}}},
+	}
+
+	classify(certs, 30, 7, time.Now())
+
+	assert.Equal(t, statusCritical, certs[0].Status)
+}
+
+func TestFormatRemaining(t *testing.T) {
+	assert.Equal(t, "3d4h", formatRemaining(3*24*time.Hour+4*time.Hour+5*time.Minute))
+	assert.Equal(t, "10h5m", formatRemaining(10*time.Hour+5*time.Minute))
+	assert.Equal(t, "59m", formatRemaining(59*time.Minute+59*time.Second))
+	assert.Equal(t, "-10h0m", formatRemaining(-10*time.Hour))
+	assert.Equal(t, "0m", formatRemaining(0))
+}
+
+func TestCheckResult(t *testing.T) {
+	assert.NoError(t, checkResult([]serverCertification{{Status: statusOK}}))
+
+	err := checkResult([]serverCertification{{Status: statusOK}, {Status: statusUnknown}})
+	assert.Equal(t, 3, err.(*ExitError).Code)
+
+	err = checkResult([]serverCertification{{Status: statusUnknown}, {Status: statusWarning}})
+	assert.Equal(t, 1, err.(*ExitError).Code)
+
+	err = checkResult([]serverCertification{{Status: statusWarning}, {Status: statusCritical}, {Status: statusCritical}})
+	assert.Equal(t, 2, err.(*ExitError).Code)
+	assert.Equal(t, "CRITICAL: 2 CRITICAL, 1 WARNING certification(s)", err.Error())
+
+	err = checkResult([]serverCertification{{Status: statusExpired}, {Status: statusCritical}})
+	assert.Equal(t, 2, err.(*ExitError).Code)
+	assert.Equal(t, "EXPIRED: 1 EXPIRED, 1 CRITICAL certification(s)", err.Error())
+}
+
+func TestCheckResultCountsBundleOnce(t *testing.T) {
+	bundle := Entry{Type: "apiserver", Node: "master", Name: "client-ca-file", Path: "/etc/kubernetes/pki/ca.crt"}
+	first, second := bundle, bundle
+	first.Position, second.Position = 1, 2
+
+	err := checkResult([]serverCertification{
+		{Entry: bundle, Status: statusExpired},
+		{Entry: first, Status: statusExpired},
+		{Entry: second, Status: statusOK},
+		{Entry: Entry{Type: "apiserver", Node: "master", Name: "tls-cert-file"}, Status: statusWarning},
+	})
+	assert.Equal(t, 2, err.(*ExitError).Code)
+	assert.Equal(t, "EXPIRED: 1 EXPIRED, 1 WARNING certification(s)", err.Error())
+}
+
+func TestReportExitsByStatusOnlyWithThresholds(t *testing.T) {
+	expired := func() []serverCertification {
+		return []serverCertification{{Entry: Entry{Type: "apiserver", Name: "tls-cert-file", Due: time.Now().Add(-time.Hour)}}}
+	}
+	o := NewExpirationOptions(genericclioptions.NewTestIOStreamsDiscard())
+	assert.NoError(t, o.validateOutput())
+
+	assert.NoError(t, o.report(expired()))
+
+	o.warnDays = 30
+	err := o.report(expired())
+	assert.Equal(t, 2, err.(*ExitError).Code)
+}
+
+func TestClassifyAt(t *testing.T) {
+	due := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	certs := []serverCertification{
+		{Entry: Entry{Days: 100, Due: due}},
+	}
+
+	classify(certs, 30, 7, due.Add(-10*24*time.Hour-time.Hour))
+	assert.Equal(t, 10, certs[0].Entry.Days)
+	assert.Equal(t, statusWarning, certs[0].Status)
+
+	classify(certs, 30, 7, due.Add(10*time.Hour))
+	assert.Equal(t, -1, certs[0].Entry.Days)
+	assert.Equal(t, statusExpired, certs[0].Status)
+}
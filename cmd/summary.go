@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cast"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const certificationSummaryListKind = "CertificationSummaryList"
+
+// certificationSummary aggregates certifications of the same type and name over nodes
+type certificationSummary struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Status is the worst status among the certifications
+	Status string `json:"status"`
+	Count  int    `json:"count"`
+	// Errors is the number of certifications which could not be collected, they are not in days
+	Errors       int       `json:"errors"`
+	MinDays      int       `json:"minDays"`
+	MedianDays   float64   `json:"medianDays"`
+	MaxDays      int       `json:"maxDays"`
+	EarliestDue  time.Time `json:"earliestDue"`
+	EarliestNode string    `json:"earliestNode"`
+}
+
+// CertificationSummaryList is the versioned document written by machine readable outputs with --summary
+type CertificationSummaryList struct {
+	meta_v1.TypeMeta `json:",inline"`
+
+	Items []certificationSummary `json:"items"`
+}
+
+// DeepCopyObject implements runtime.Object so that kubectl printers can be used
+func (l *CertificationSummaryList) DeepCopyObject() runtime.Object {
+	out := *l
+	out.Items = append([]certificationSummary(nil), l.Items...)
+	return &out
+}
+
+// summarize aggregates certifications by type and name. Each certificate of a bundle is not counted.
+func summarize(certs []serverCertification) []certificationSummary {
+	groups := map[[2]string][]serverCertification{}
+	keys := [][2]string{}
+	for _, v := range certs {
+		if v.Entry.Position != 0 {
+			continue
+		}
+		key := [2]string{v.Entry.Type, v.Entry.Name}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], v)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return lessByDefault(Entry{Type: keys[i][0], Name: keys[i][1]}, Entry{Type: keys[j][0], Name: keys[j][1]})
+	})
+
+	summaries := []certificationSummary{}
+	for _, key := range keys {
+		summaries = append(summaries, summarizeGroup(key[0], key[1], groups[key]))
+	}
+	return summaries
+}
+
+func summarizeGroup(entryType string, name string, certs []serverCertification) certificationSummary {
+	s := certificationSummary{
+		Type:   entryType,
+		Name:   name,
+		Status: statusOK,
+		Count:  len(certs),
+	}
+	days := []int{}
+	for _, v := range certs {
+		if severities[v.Status] > severities[s.Status] {
+			s.Status = v.Status
+		}
+		if v.Entry.Due.IsZero() {
+			s.Errors++
+			continue
+		}
+		days = append(days, v.Entry.Days)
+		if s.EarliestDue.IsZero() || v.Entry.Due.Before(s.EarliestDue) {
+			s.EarliestDue = v.Entry.Due
+			s.EarliestNode = v.Entry.Node
+		}
+	}
+	if len(days) == 0 {
+		return s
+	}
+
+	sort.Ints(days)
+	s.MinDays = days[0]
+	s.MaxDays = days[len(days)-1]
+	if len(days)%2 == 1 {
+		s.MedianDays = float64(days[len(days)/2])
+	} else {
+		s.MedianDays = float64(days[len(days)/2-1]+days[len(days)/2]) / 2
+	}
+	return s
+}
+
+func (o *ExpirationOptions) printSummary(summaries []certificationSummary) error {
+	format, arg := o.outputFormat()
+	switch format {
+	case tableOutput, wideOutput:
+		return printSummaryTable(o.Out, summaries)
+	case csvOutput:
+		return printSummaryCSV(o.Out, summaries)
+	case customColumnsOutput, customColumnsFileOutput:
+		p, err := newCustomColumnsPrinter(format, arg)
+		if err != nil {
+			return err
+		}
+		items := make([]interface{}, len(summaries))
+		for i := range summaries {
+			items[i] = summaries[i]
+		}
+		return p.printItems(o.Out, items)
+	}
+
+	p, err := o.printFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	return p.PrintObj(&CertificationSummaryList{
+		TypeMeta: meta_v1.TypeMeta{
+			APIVersion: certificationListAPIVersion,
+			Kind:       certificationSummaryListKind,
+		},
+		Items: summaries,
+	}, o.Out)
+}
+
+// summaryFields are columns of a summary shared by the table and csv
+func summaryFields(s certificationSummary) []string {
+	due := ""
+	median := ""
+	if !s.EarliestDue.IsZero() {
+		due = s.EarliestDue.Format(time.RFC3339)
+		median = fmt.Sprintf("%g", s.MedianDays)
+	}
+	return []string{s.Type, s.Name, s.Status, cast.ToString(s.Count), cast.ToString(s.Errors),
+		cast.ToString(s.MinDays), median, cast.ToString(s.MaxDays), due, s.EarliestNode}
+}
+
+func printSummaryTable(out io.Writer, summaries []certificationSummary) error {
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Type", "Name", "Status", "Count", "Errors", "Min Days", "Median Days", "Max Days", "Earliest Due", "Earliest Node"})
+
+	for _, s := range summaries {
+		table.Append(summaryFields(s))
+	}
+	table.Render() // Send output
+
+	return nil
+}
+
+func printSummaryCSV(out io.Writer, summaries []certificationSummary) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"type", "name", "status", "count", "errors", "min_days", "median_days", "max_days", "earliest_due", "earliest_node"}); err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		if err := w.Write(summaryFields(s)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func summaryTestCertifications() []serverCertification {
+	due := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return []serverCertification{
+		{Entry: Entry{Type: "kubelet", Node: "node1", Name: "server-cert", Days: 30, Due: due.Add(30 * 24 * time.Hour)}, Status: statusOK},
+		{Entry: Entry{Type: "kubelet", Node: "node2", Name: "server-cert", Days: 10, Due: due.Add(10 * 24 * time.Hour)}, Status: statusWarning},
+		{Entry: Entry{Type: "kubelet", Node: "node3", Name: "server-cert", Days: 20, Due: due.Add(20 * 24 * time.Hour)}, Status: statusOK},
+		{Entry: Entry{Type: "kubelet", Node: "node4", Name: "server-cert", Days: 40, Due: due.Add(40 * 24 * time.Hour)}, Status: statusOK},
+		{Entry: Entry{Type: "kubelet", Node: "node5", Name: "Error"}, Status: statusUnknown},
+		{Entry: Entry{Type: "apiserver", Node: "master", Name: "client-ca-file", Days: 300, Due: due.Add(300 * 24 * time.Hour)}, Status: statusOK},
+		// every certificate of a bundle is not counted
+		{Entry: Entry{Type: "apiserver", Node: "master", Name: "client-ca-file", Days: 300, Due: due.Add(300 * 24 * time.Hour), Position: 1}, Status: statusOK},
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	summaries := summarize(summaryTestCertifications())
+
+	assert.Equal(t, 3, len(summaries))
+	assert.Equal(t, certificationSummary{
+		Type:         "apiserver",
+		Name:         "client-ca-file",
+		Status:       statusOK,
+		Count:        1,
+		MinDays:      300,
+		MedianDays:   300,
+		MaxDays:      300,
+		EarliestDue:  time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC).Add(300 * 24 * time.Hour),
+		EarliestNode: "master",
+	}, summaries[0])
+
+	assert.Equal(t, "Error", summaries[1].Name)
+	assert.Equal(t, 1, summaries[1].Errors)
+	assert.Equal(t, statusUnknown, summaries[1].Status)
+	assert.True(t, summaries[1].EarliestDue.IsZero())
+
+	assert.Equal(t, "server-cert", summaries[2].Name)
+	assert.Equal(t, statusWarning, summaries[2].Status)
+	assert.Equal(t, 4, summaries[2].Count)
+	assert.Equal(t, 10, summaries[2].MinDays)
+	assert.Equal(t, 25.0, summaries[2].MedianDays)
+	assert.Equal(t, 40, summaries[2].MaxDays)
+	assert.Equal(t, "node2", summaries[2].EarliestNode)
+}
+
+func printSummaryWithOutput(t *testing.T, output string) string {
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewExpirationOptions(streams)
+	o.summary = true
+	*o.printFlags.OutputFormat = output
+
+	assert.NoError(t, o.validateOutput())
+	assert.NoError(t, o.printCertifications(summaryTestCertifications()))
+	return out.String()
+}
+
+func TestPrintSummaryCSV(t *testing.T) {
+	assert.Equal(t, "type,name,status,count,errors,min_days,median_days,max_days,earliest_due,earliest_node\n"+
+		"apiserver,client-ca-file,OK,1,0,300,300,300,2019-10-28T00:00:00Z,master\n"+
+		"kubelet,Error,UNKNOWN,1,1,0,,0,,\n"+
+		"kubelet,server-cert,WARNING,4,0,10,25,40,2019-01-11T00:00:00Z,node2\n", printSummaryWithOutput(t, "csv"))
+}
+
+func TestPrintSummaryJSONPath(t *testing.T) {
+	out := printSummaryWithOutput(t, `jsonpath={.kind}{range .items[*]}/{.name}={.minDays}{end}`)
+
+	assert.Equal(t, "CertificationSummaryList/client-ca-file=300/Error=0/server-cert=10", out)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultMaxClockSkew is how far the clock of a node may deviate from this client
+const defaultMaxClockSkew = 30 * time.Second
+
+// auditNotBefore adds warnings to certifications which are not valid yet,
+// by the clock of their node if it is known or by now otherwise
+func auditNotBefore(certs []serverCertification, now time.Time) {
+	for i := range certs {
+		v := &certs[i]
+		if v.Entry.Position != 0 {
+			continue
+		}
+		at, by := now, ""
+		if !v.clock.IsZero() {
+			at, by = v.clock, " by the clock of the node"
+		}
+		for _, c := range v.certs {
+			if at.Before(c.NotBefore) {
+				v.addWarning(fmt.Sprintf("%q is not valid before %s%s", c.Subject.CommonName, c.NotBefore, by))
+			}
+		}
+	}
+}
+
+// clockSkewWarning compares the time of a node with the middle of sent and received of its response
+// and returns a warning if they differ by more than maxSkew
+func clockSkewWarning(nodeTime time.Time, sent time.Time, received time.Time, maxSkew time.Duration) string {
+	local := sent.Add(received.Sub(sent) / 2)
+	skew := nodeTime.Sub(local)
+	switch {
+	case skew > maxSkew:
+		return fmt.Sprintf("clock of the node is %s ahead", skew.Round(time.Second))
+	case -skew > maxSkew:
+		return fmt.Sprintf("clock of the node is %s behind", (-skew).Round(time.Second))
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditNotBefore(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour).UTC().Truncate(time.Second)
+
+	certs := append(newCertifications("apiserver", "node1", "tls-cert-file", "apiserver.crt", newTestCert(t, "apiserver", future, now.Add(24*time.Hour)), nil),
+		newCertifications("apiserver", "node1", "etcd-certfile", "etcd.crt", newTestCert(t, "etcd", now.Add(-time.Hour), now.Add(24*time.Hour)), nil)...)
+	kubelet := newCertifications("kubelet", "node1", "server-cert", "kubelet.crt", newTestCert(t, "kubelet", future, now.Add(24*time.Hour)), nil)
+	// the clock of the node is already after the renewal
+	kubelet[0].clock = now.Add(2 * time.Hour)
+	certs = append(certs, kubelet...)
+
+	auditNotBefore(certs, now)
+
+	assert.Equal(t, `"apiserver" is not valid before `+future.String(), certs[0].Warning)
+	assert.Equal(t, "", certs[1].Warning)
+	assert.Equal(t, "", certs[2].Warning)
+
+	kubelet[0].clock = now
+	auditNotBefore(kubelet, now)
+	assert.Equal(t, `"kubelet" is not valid before `+future.String()+" by the clock of the node", kubelet[0].Warning)
+}
+
+func TestClockSkewWarning(t *testing.T) {
+	sent := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	received := sent.Add(2 * time.Second)
+
+	assert.Equal(t, "", clockSkewWarning(sent.Add(time.Second), sent, received, 30*time.Second))
+	assert.Equal(t, "", clockSkewWarning(sent.Add(31*time.Second), sent, received, 30*time.Second))
+	assert.Equal(t, "clock of the node is 5m0s ahead", clockSkewWarning(sent.Add(5*time.Minute+time.Second), sent, received, 30*time.Second))
+	assert.Equal(t, "clock of the node is 1m0s behind", clockSkewWarning(sent.Add(-59*time.Second), sent, received, 30*time.Second))
+}
@@ -19,6 +19,7 @@ require (
 	github.com/modern-go/reflect2 v1.0.1 // indirect
 	github.com/olekukonko/tablewriter v0.0.1
 	github.com/peterbourgon/diskv v2.0.1+incompatible // indirect
+	github.com/prometheus/client_golang v0.9.1
 	github.com/prometheus/common v0.0.0-20190107103113-2998b132700a
 	github.com/spf13/cast v1.3.0
 	github.com/spf13/cobra v0.0.3
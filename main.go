@@ -17,6 +17,9 @@ func main() {
 		genericclioptions.IOStreams{
 			In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
 	if err := root.Execute(); err != nil {
+		if e, ok := err.(*cmd.ExitError); ok {
+			os.Exit(e.Code)
+		}
 		os.Exit(1)
 	}
 }